@@ -0,0 +1,178 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/firewall"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type Signal struct {
+	Name string
+	Num  syscall.Signal
+}
+
+// Signals are the choices offered when terminating a socket's owner, the
+// polite ones first.
+var Signals = []Signal{
+	{"SIGTERM", syscall.SIGTERM},
+	{"SIGINT", syscall.SIGINT},
+	{"SIGHUP", syscall.SIGHUP},
+	{"SIGKILL", syscall.SIGKILL},
+	{"SIGSTOP", syscall.SIGSTOP},
+	{"SIGCONT", syscall.SIGCONT},
+}
+
+func Lookup(name string) (Signal, bool) {
+	for _, s := range Signals {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Signal{}, false
+}
+
+type Options struct {
+	// DryRun reports what each action would do instead of doing it.
+	DryRun bool
+	// ApplyFirewall lets the firewall preview run its commands.
+	ApplyFirewall bool
+}
+
+// Runner carries out the TUI's process, socket and firewall actions on the
+// local host.
+type Runner struct {
+	collector *connections.Collector
+	dryRun    bool
+	firewall  bool
+}
+
+func New(collector *connections.Collector, opts Options) *Runner {
+	return &Runner{collector: collector, dryRun: opts.DryRun, firewall: opts.ApplyFirewall}
+}
+
+func (r *Runner) DryRun() bool {
+	return r.dryRun
+}
+
+func (r *Runner) CanApplyFirewall() bool {
+	return r.firewall
+}
+
+// Owners lists every process holding the connection's socket, falling back
+// to what the snapshot recorded if the socket is already gone.
+func (r *Runner) Owners(conn models.ConnectionItem) []string {
+	if owners := r.collector.SocketOwners(conn.Inode); len(owners) > 0 {
+		return owners
+	}
+
+	var owners []string
+	seen := make(map[string]bool)
+	add := func(pid string) {
+		if _, err := strconv.Atoi(pid); err == nil && !seen[pid] {
+			seen[pid] = true
+			owners = append(owners, pid)
+		}
+	}
+	add(conn.PID)
+	for _, ref := range conn.Stdio {
+		add(ref.PID)
+	}
+	return owners
+}
+
+func (r *Runner) Signal(pids []string, name string) (string, error) {
+	sig, ok := Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("unknown signal %s", name)
+	}
+	if len(pids) == 0 {
+		return "", fmt.Errorf("no process owns this socket")
+	}
+
+	var sent []string
+	var errs []error
+	for _, pid := range pids {
+		n, err := checkPID(pid)
+		if err == nil && !r.dryRun {
+			err = signalPID(n, sig)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent = append(sent, pid)
+	}
+
+	status := ""
+	if len(sent) > 0 {
+		status = fmt.Sprintf("Sent %s to PID %s", sig.Name, strings.Join(sent, ", "))
+		if r.dryRun {
+			status = "Dry run: would send " + strings.TrimPrefix(status, "Sent ")
+		}
+	}
+	return status, errors.Join(errs...)
+}
+
+func checkPID(pid string) (int, error) {
+	n, err := strconv.Atoi(pid)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid PID %q", pid)
+	}
+	if n == os.Getpid() {
+		return 0, fmt.Errorf("refusing to signal StatTUI itself (PID %d)", n)
+	}
+	return n, nil
+}
+
+func signalPID(n int, sig Signal) error {
+	err := syscall.Kill(n, sig.Num)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("permission denied sending %s to PID %d: it belongs to another user (needs root or CAP_KILL)", sig.Name, n)
+	case errors.Is(err, syscall.ESRCH):
+		return fmt.Errorf("PID %d has already exited", n)
+	}
+	return fmt.Errorf("kill %d: %v", n, err)
+}
+
+func (r *Runner) Destroy(conn models.ConnectionItem) (string, error) {
+	if !strings.HasPrefix(conn.Proto, "TCP") {
+		return "", fmt.Errorf("only TCP sockets can be destroyed, not %s", conn.Proto)
+	}
+
+	target := fmt.Sprintf("%s %s → %s", conn.Proto, conn.Local, conn.Remote)
+	if r.dryRun {
+		return "Dry run: would destroy " + target, nil
+	}
+	if err := connections.DestroySocket(conn); err != nil {
+		return "", err
+	}
+	return "Destroyed " + target, nil
+}
+
+func (r *Runner) ApplyFirewall(commands [][]string) (string, error) {
+	if !r.firewall {
+		return "", fmt.Errorf("applying firewall rules is disabled (start with -apply-firewall)")
+	}
+	if r.dryRun {
+		return fmt.Sprintf("Dry run: would run %d firewall commands", len(commands)), nil
+	}
+
+	cmds := make([]firewall.Command, len(commands))
+	for i, argv := range commands {
+		cmds[i] = argv
+	}
+	if err := firewall.Apply(cmds); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Applied %d firewall commands", len(commands)), nil
+}
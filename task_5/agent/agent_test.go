@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type stubCollector struct {
+	mu    sync.Mutex
+	conns []models.ConnectionItem
+}
+
+func (s *stubCollector) set(conns ...models.ConnectionItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns = conns
+}
+
+func (s *stubCollector) Collect() (*models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.Snapshot{
+		Host:        "stub",
+		Time:        time.Now(),
+		Connections: append([]models.ConnectionItem(nil), s.conns...),
+	}, nil
+}
+
+func (s *stubCollector) Hostname() string {
+	return "stub"
+}
+
+func conn(local string, localPort int, remote string, remotePort int, state string) models.ConnectionItem {
+	return models.ConnectionItem{
+		Proto:      "TCP",
+		Local:      net.JoinHostPort(local, strconv.Itoa(localPort)),
+		Remote:     net.JoinHostPort(remote, strconv.Itoa(remotePort)),
+		LocalIP:    local,
+		LocalPort:  localPort,
+		RemoteIP:   remote,
+		RemotePort: remotePort,
+		State:      state,
+	}
+}
+
+func listenLoopback(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+// waitFor polls the client until its view matches want.
+func waitFor(t *testing.T, client *Client, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	var got []string
+	for time.Now().Before(deadline) {
+		got = nil
+		if snapshot, err := client.Collect(); err == nil {
+			for _, c := range snapshot.Connections {
+				got = append(got, c.State)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") == strings.Join(want, ",") {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("client states = %v, want %v", got, want)
+}
+
+func TestAgentLoopback(t *testing.T) {
+	collector := &stubCollector{}
+	collector.set(conn("10.0.0.1", 22, "10.0.0.2", 40000, "ESTABLISHED"))
+
+	a := NewAgent(collector, time.Hour)
+	a.poll()
+
+	listener := listenLoopback(t)
+	go a.Serve(listener)
+
+	client, err := NewClient(listener.Addr().String(), ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, client, "ESTABLISHED")
+
+	if host := client.Hostname(); host != "stub" {
+		t.Errorf("Hostname() = %q, want stub", host)
+	}
+
+	// Each further poll reaches the client as a diff.
+	collector.set(
+		conn("10.0.0.1", 22, "10.0.0.2", 40000, "FIN_WAIT1"),
+		conn("10.0.0.1", 22, "10.0.0.3", 40001, "ESTABLISHED"),
+	)
+	a.poll()
+	waitFor(t, client, "ESTABLISHED", "FIN_WAIT1")
+
+	collector.set(conn("10.0.0.1", 22, "10.0.0.3", 40001, "ESTABLISHED"))
+	a.poll()
+	waitFor(t, client, "ESTABLISHED")
+}
+
+func TestClientResyncsAfterGap(t *testing.T) {
+	listener := listenLoopback(t)
+
+	sessions := make(chan int, 4)
+	go func() {
+		for n := 1; ; n++ {
+			nc, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fakeAgent(nc, n)
+			sessions <- n
+		}
+	}()
+
+	client, err := NewClient(listener.Addr().String(), ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first session skips sequence 2, so the client must drop it and
+	// pick up the full snapshot the second session starts with.
+	waitFor(t, client, "SYN_SENT")
+
+	select {
+	case n := <-sessions:
+		if n != 1 {
+			t.Fatalf("first session = %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no session")
+	}
+	select {
+	case <-sessions:
+	case <-time.After(time.Second):
+		t.Fatal("client did not reconnect")
+	}
+}
+
+func fakeAgent(nc net.Conn, session int) {
+	defer nc.Close()
+	c := newCodec(nc)
+	if _, err := c.receive(); err != nil {
+		return
+	}
+	c.send(Message{Type: MsgHello, Version: ProtocolVersion, Host: "fake"})
+
+	first := &models.Snapshot{Host: "fake", Connections: []models.ConnectionItem{
+		conn("10.0.0.1", 22, "10.0.0.2", 40000, "ESTABLISHED"),
+	}}
+
+	if session == 1 {
+		c.send(Message{Type: MsgSnapshot, Seq: 1, Snapshot: first})
+		c.send(Message{Type: MsgDiff, Seq: 3, Diff: &models.SnapshotDiff{
+			Added: []models.ConnectionItem{conn("10.0.0.1", 22, "10.0.0.4", 40002, "CLOSE_WAIT")},
+		}})
+	} else {
+		c.send(Message{Type: MsgSnapshot, Seq: 5, Snapshot: &models.Snapshot{Host: "fake", Connections: []models.ConnectionItem{
+			conn("10.0.0.1", 22, "10.0.0.5", 40003, "SYN_SENT"),
+		}}})
+	}
+
+	// Hold the session open until the client hangs up.
+	io.Copy(io.Discard, nc)
+}
+
+func TestMatchFlowsDuplicateTuple(t *testing.T) {
+	client := conn("10.0.0.5", 40000, "10.0.0.9", 443, "ESTABLISHED")
+	server := conn("10.0.0.9", 443, "10.0.0.5", 40000, "ESTABLISHED")
+
+	// host-b reports the same server-side tuple as host-a from an
+	// overlapping private range; it must not hide host-a's real peer.
+	a, b, c := server, client, server
+	a.Host, b.Host, c.Host = "host-a", "host-b", "host-b"
+
+	matched := MatchFlows([]models.ConnectionItem{a, b, c})
+	for _, m := range matched {
+		if m.Host == "host-b" && m.LocalPort == 40000 && m.PeerHost != "host-a" {
+			t.Errorf("client on host-b matched peer %q, want host-a", m.PeerHost)
+		}
+	}
+}
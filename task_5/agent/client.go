@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type ClientOptions struct {
+	TLS                bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+	DialTimeout        time.Duration
+}
+
+type Client struct {
+	addr    string
+	opts    ClientOptions
+	tlsConf *tls.Config
+
+	mu       sync.Mutex
+	host     string
+	latest   *models.Snapshot
+	lastErr  error
+	lastSeen time.Time
+	started  bool
+}
+
+func NewClient(addr string, opts ClientOptions) (*Client, error) {
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+
+	c := &Client{addr: addr, opts: opts, host: addr}
+
+	if opts.TLS {
+		conf, err := opts.tlsConfig(addr)
+		if err != nil {
+			return nil, err
+		}
+		c.tlsConf = conf
+	}
+
+	return c, nil
+}
+
+func (o ClientOptions) tlsConfig(addr string) (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if conf.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			conf.ServerName = host
+		}
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	if strings.HasPrefix(c.addr, "unix:") {
+		return net.DialTimeout("unix", strings.TrimPrefix(c.addr, "unix:"), c.opts.DialTimeout)
+	}
+
+	if c.tlsConf != nil {
+		dialer := &net.Dialer{Timeout: c.opts.DialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", c.addr, c.tlsConf)
+	}
+
+	return net.DialTimeout("tcp", c.addr, c.opts.DialTimeout)
+}
+
+func (c *Client) Start() {
+	c.mu.Lock()
+	if c.started {
+		c.mu.Unlock()
+		return
+	}
+	c.started = true
+	c.mu.Unlock()
+
+	go c.loop()
+}
+
+// errResync ends a session whose diff stream skipped a sequence number;
+// reconnecting makes the agent start over with a full snapshot.
+var errResync = errors.New("missed an update, resynchronising")
+
+func (c *Client) loop() {
+	backoff := time.Second
+	resynced := false
+	for {
+		started := time.Now()
+		err := c.session()
+		if errors.Is(err, errResync) && !resynced {
+			resynced = true
+			continue
+		}
+		resynced = false
+		c.setError(err)
+
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (c *Client) session() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	codec := newCodec(conn)
+	if err := codec.send(Message{Type: MsgHello, Version: ProtocolVersion}); err != nil {
+		return err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(c.opts.DialTimeout))
+	hello, err := codec.receive()
+	if err != nil {
+		return err
+	}
+	if hello.Type != MsgHello {
+		return fmt.Errorf("unexpected %q message during handshake", hello.Type)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c.mu.Lock()
+	c.host = hello.Host
+	c.latest = nil
+	c.mu.Unlock()
+
+	var seq uint64
+	for {
+		msg, err := codec.receive()
+		if err != nil {
+			if msg.Type == MsgError {
+				c.setError(err)
+				continue
+			}
+			return err
+		}
+
+		c.mu.Lock()
+		switch msg.Type {
+		case MsgSnapshot:
+			if msg.Snapshot != nil {
+				c.latest = msg.Snapshot
+				seq = msg.Seq
+			}
+		case MsgDiff:
+			if c.latest == nil || msg.Seq != seq+1 {
+				c.mu.Unlock()
+				return errResync
+			}
+			if msg.Diff != nil {
+				c.latest = connections.ApplyDiff(c.latest, *msg.Diff)
+			}
+			seq = msg.Seq
+		}
+		c.lastErr = nil
+		c.lastSeen = time.Now()
+		c.mu.Unlock()
+	}
+}
+
+func (c *Client) setError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastErr = err
+}
+
+func (c *Client) Collect() (*models.Snapshot, error) {
+	c.Start()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.latest == nil {
+		if c.lastErr != nil {
+			return nil, fmt.Errorf("agent %s: %v", c.addr, c.lastErr)
+		}
+		return nil, fmt.Errorf("agent %s: waiting for first snapshot", c.addr)
+	}
+
+	snapshot := *c.latest
+	snapshot.Connections = append([]models.ConnectionItem(nil), c.latest.Connections...)
+	if c.lastErr != nil {
+		snapshot.Errors = append(append([]string(nil), snapshot.Errors...), c.lastErr.Error())
+	}
+	return &snapshot, nil
+}
+
+func (c *Client) Hostname() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.host
+}
+
+func (c *Client) Health() []models.HostHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := models.HostHealth{
+		Host:     c.host,
+		Addr:     c.addr,
+		LastSeen: c.lastSeen,
+	}
+	if c.lastErr != nil {
+		health.Error = c.lastErr.Error()
+	}
+	if c.latest != nil {
+		health.Connections = len(c.latest.Connections)
+	}
+	return []models.HostHealth{health}
+}
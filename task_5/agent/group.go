@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type Group struct {
+	clients []*Client
+}
+
+func NewGroup(addrs []string, opts ClientOptions) (*Group, error) {
+	g := &Group{}
+	for _, addr := range addrs {
+		client, err := NewClient(addr, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", addr, err)
+		}
+		g.clients = append(g.clients, client)
+	}
+	return g, nil
+}
+
+func (g *Group) Start() {
+	for _, client := range g.clients {
+		client.Start()
+	}
+}
+
+func (g *Group) Hostname() string {
+	return fmt.Sprintf("%d hosts", len(g.clients))
+}
+
+func (g *Group) Health() []models.HostHealth {
+	var health []models.HostHealth
+	for _, client := range g.clients {
+		health = append(health, client.Health()...)
+	}
+	return health
+}
+
+func (g *Group) Collect() (*models.Snapshot, error) {
+	type result struct {
+		snapshot *models.Snapshot
+		err      error
+	}
+
+	results := make([]result, len(g.clients))
+	var wg sync.WaitGroup
+	for i, client := range g.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			snapshot, err := client.Collect()
+			results[i] = result{snapshot, err}
+		}(i, client)
+	}
+	wg.Wait()
+
+	merged := &models.Snapshot{Host: g.Hostname(), Time: time.Now()}
+	var errors []string
+
+	for i, r := range results {
+		if r.err != nil {
+			errors = append(errors, r.err.Error())
+			continue
+		}
+
+		host := r.snapshot.Host
+		if host == "" {
+			host = g.clients[i].addr
+		}
+
+		for _, conn := range r.snapshot.Connections {
+			conn.Host = host
+			merged.Connections = append(merged.Connections, conn)
+		}
+		for _, entry := range r.snapshot.Sockstat {
+			entry.Host = host
+			merged.Sockstat = append(merged.Sockstat, entry)
+		}
+		for _, counter := range r.snapshot.Counters {
+			counter.Host = host
+			merged.Counters = append(merged.Counters, counter)
+		}
+		for _, e := range r.snapshot.Errors {
+			errors = append(errors, host+": "+e)
+		}
+
+		merged.Ownership.ProcessesScanned += r.snapshot.Ownership.ProcessesScanned
+		merged.Ownership.ProcessesDenied += r.snapshot.Ownership.ProcessesDenied
+		merged.Ownership.SocketFDs += r.snapshot.Ownership.SocketFDs
+		merged.Ownership.Resolved += r.snapshot.Ownership.Resolved
+		merged.Ownership.Unresolved += r.snapshot.Ownership.Unresolved
+		if r.snapshot.Duration > merged.Duration {
+			merged.Duration = r.snapshot.Duration
+		}
+	}
+
+	merged.Errors = errors
+	if len(errors) == len(g.clients) && len(errors) > 0 {
+		return nil, fmt.Errorf("all agents failed: %s", strings.Join(errors, "; "))
+	}
+
+	merged.Connections = MatchFlows(merged.Connections)
+	return merged, nil
+}
+
+// MatchFlows links the two ends of a TCP or UDP flow observed on different
+// monitored hosts and reorders the list so each pair is adjacent.
+func MatchFlows(conns []models.ConnectionItem) []models.ConnectionItem {
+	// Overlapping private ranges mean several hosts can report the same
+	// tuple, so every position is kept and the peer is picked by host.
+	index := make(map[string][]int, len(conns))
+	for i, conn := range conns {
+		if key, ok := flowKey(conn.LocalIP, conn.LocalPort, conn.RemoteIP, conn.RemotePort); ok {
+			index[key] = append(index[key], i)
+		}
+	}
+
+	peers := make(map[int]int)
+	for i, conn := range conns {
+		key, ok := flowKey(conn.RemoteIP, conn.RemotePort, conn.LocalIP, conn.LocalPort)
+		if !ok {
+			continue
+		}
+		for _, j := range index[key] {
+			if conns[j].Host == conn.Host || baseProto(conns[j].Proto) != baseProto(conn.Proto) {
+				continue
+			}
+			peers[i] = j
+			conns[i].PeerHost = conns[j].Host
+			conns[i].PeerPID = conns[j].PID
+			conns[i].PeerProc = conns[j].Process
+			break
+		}
+	}
+
+	ordered := make([]models.ConnectionItem, 0, len(conns))
+	emitted := make([]bool, len(conns))
+	for i := range conns {
+		if emitted[i] {
+			continue
+		}
+		ordered = append(ordered, conns[i])
+		emitted[i] = true
+		if j, ok := peers[i]; ok && !emitted[j] {
+			ordered = append(ordered, conns[j])
+			emitted[j] = true
+		}
+	}
+	return ordered
+}
+
+func flowKey(localIP string, localPort int, remoteIP string, remotePort int) (string, bool) {
+	local, err := netip.ParseAddr(localIP)
+	if err != nil {
+		return "", false
+	}
+	remote, err := netip.ParseAddr(remoteIP)
+	if err != nil {
+		return "", false
+	}
+	local, remote = local.Unmap(), remote.Unmap()
+
+	if remotePort == 0 || local.IsUnspecified() || remote.IsUnspecified() || local.IsLoopback() || remote.IsLoopback() {
+		return "", false
+	}
+
+	return local.String() + ":" + strconv.Itoa(localPort) + ">" + remote.String() + ":" + strconv.Itoa(remotePort), true
+}
+
+func baseProto(proto string) string {
+	return strings.TrimSuffix(proto, "6")
+}
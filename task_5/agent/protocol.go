@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// The agent protocol is a stream of newline-delimited JSON messages. The
+// client opens with a hello, the agent answers with its own hello and a full
+// snapshot, then pushes a diff against the previous state on every tick.
+// Periodic full snapshots let clients recover from any drift.
+
+const ProtocolVersion = 1
+
+const (
+	MsgHello    = "hello"
+	MsgSnapshot = "snapshot"
+	MsgDiff     = "diff"
+	MsgError    = "error"
+)
+
+type Message struct {
+	Type     string               `json:"type"`
+	Version  int                  `json:"version,omitempty"`
+	Host     string               `json:"host,omitempty"`
+	Seq      uint64               `json:"seq,omitempty"`
+	Snapshot *models.Snapshot     `json:"snapshot,omitempty"`
+	Diff     *models.SnapshotDiff `json:"diff,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+type codec struct {
+	dec *json.Decoder
+	enc *json.Encoder
+	w   *bufio.Writer
+}
+
+func newCodec(rw io.ReadWriter) *codec {
+	w := bufio.NewWriter(rw)
+	return &codec{
+		dec: json.NewDecoder(bufio.NewReader(rw)),
+		enc: json.NewEncoder(w),
+		w:   w,
+	}
+}
+
+func (c *codec) send(msg Message) error {
+	if err := c.enc.Encode(msg); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func (c *codec) receive() (Message, error) {
+	var msg Message
+	if err := c.dec.Decode(&msg); err != nil {
+		return msg, err
+	}
+	if msg.Type == MsgError {
+		return msg, fmt.Errorf("agent error: %s", msg.Error)
+	}
+	return msg, nil
+}
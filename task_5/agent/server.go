@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+const resyncEvery = 12
+
+type Agent struct {
+	collector models.Collector
+	interval  time.Duration
+
+	mu          sync.Mutex
+	latest      *models.Snapshot
+	seq         uint64
+	subscribers map[chan Message]bool
+}
+
+func NewAgent(collector models.Collector, interval time.Duration) *Agent {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Agent{
+		collector:   collector,
+		interval:    interval,
+		subscribers: make(map[chan Message]bool),
+	}
+}
+
+func (a *Agent) Run(ctx context.Context) {
+	a.poll()
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.poll()
+		}
+	}
+}
+
+func (a *Agent) poll() {
+	snapshot, err := a.collector.Collect()
+	if err != nil {
+		a.broadcast(Message{Type: MsgError, Error: err.Error()})
+		return
+	}
+
+	a.mu.Lock()
+	prev := a.latest
+	a.latest = snapshot
+	a.seq++
+	seq := a.seq
+	a.mu.Unlock()
+
+	if prev == nil || seq%resyncEvery == 0 {
+		a.broadcast(Message{Type: MsgSnapshot, Seq: seq, Snapshot: snapshot})
+		return
+	}
+
+	diff := connections.DiffSnapshots(prev, snapshot)
+	a.broadcast(Message{Type: MsgDiff, Seq: seq, Diff: &diff})
+}
+
+func (a *Agent) broadcast(msg Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for ch := range a.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			// A client that cannot keep up is dropped; it will reconnect
+			// and start again from a full snapshot.
+			delete(a.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (a *Agent) subscribe() (chan Message, *models.Snapshot, uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ch := make(chan Message, 16)
+	a.subscribers[ch] = true
+	return ch, a.latest, a.seq
+}
+
+func (a *Agent) unsubscribe(ch chan Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.subscribers[ch] {
+		delete(a.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (a *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	c := newCodec(conn)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	hello, err := c.receive()
+	if err != nil || hello.Type != MsgHello {
+		return
+	}
+	if hello.Version != ProtocolVersion {
+		c.send(Message{Type: MsgError, Error: fmt.Sprintf("unsupported protocol version %d", hello.Version)})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if err := c.send(Message{Type: MsgHello, Version: ProtocolVersion, Host: a.collector.Hostname()}); err != nil {
+		return
+	}
+
+	ch, latest, seq := a.subscribe()
+	defer a.unsubscribe(ch)
+
+	if latest != nil {
+		if err := c.send(Message{Type: MsgSnapshot, Seq: seq, Snapshot: latest}); err != nil {
+			return
+		}
+	}
+
+	// Detect the client going away even while no updates are pending.
+	closed := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		conn.Read(buf)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Type == MsgDiff && (latest == nil || msg.Seq <= seq) {
+				continue
+			}
+			if msg.Type == MsgSnapshot {
+				latest = msg.Snapshot
+			}
+			if msg.Seq > seq {
+				seq = msg.Seq
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.send(msg); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/agent"
+	"github.com/mizerael/infsec_ssu/task_5/api"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+)
+
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:7070", "TCP address or unix:/path/to/socket to accept TUI clients on")
+	socketMode := fs.String("socket-mode", "0660", "permissions for the unix socket")
+	procRoot := fs.String("proc", "/proc", "procfs mount to read sockets and processes from")
+	interval := fs.Duration("interval", 5*time.Second, "how often to collect and push an update")
+	certFile := fs.String("tls-cert", "", "TLS certificate (enables TLS on TCP listeners)")
+	keyFile := fs.String("tls-key", "", "TLS private key")
+	clientCA := fs.String("client-ca", "", "require client certificates signed by this CA")
+	fs.Parse(args)
+
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil {
+		fmt.Printf("Error: invalid socket mode %q\n", *socketMode)
+		os.Exit(1)
+	}
+
+	listener, err := api.Listen(*listen, os.FileMode(mode))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	tlsOpts := agent.ServerTLS{CertFile: *certFile, KeyFile: *keyFile, ClientCAFile: *clientCA}
+	if tlsOpts.Enabled() {
+		if listener, err = agent.WrapTLS(listener, tlsOpts); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if !strings.HasPrefix(*listen, "unix:") {
+		fmt.Println("Warning: serving agent over plain TCP; use -tls-cert/-tls-key outside trusted networks")
+	}
+
+	a := agent.NewAgent(connections.NewCollector(*procRoot), *interval)
+	go a.Run(context.Background())
+
+	fmt.Printf("Agent listening on %s\n", *listen)
+	if err := a.Serve(listener); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
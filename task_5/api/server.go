@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/detect"
+	"github.com/mizerael/infsec_ssu/task_5/history"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type Server struct {
+	collector models.Collector
+	interval  time.Duration
+	history   *history.History
+
+	mu      sync.RWMutex
+	latest  *models.Snapshot
+	lastErr string
+}
+
+type ProcessSummary struct {
+	PID         string         `json:"pid"`
+	Process     string         `json:"process"`
+	User        string         `json:"user"`
+	Sockets     int            `json:"sockets"`
+	States      map[string]int `json:"states"`
+	ListenPorts []int          `json:"listen_ports"`
+	Remotes     []string       `json:"remotes"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewServer(collector models.Collector, interval time.Duration, h *history.History) *Server {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if h == nil {
+		h = history.New(0)
+	}
+	return &Server{collector: collector, interval: interval, history: h}
+}
+
+func (s *Server) Run(ctx context.Context) {
+	s.refresh()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh()
+		}
+	}
+}
+
+func (s *Server) refresh() {
+	snapshot, err := s.collector.Collect()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.lastErr = err.Error()
+		return
+	}
+
+	if s.latest != nil {
+		s.history.Add(connections.DiffEvents(s.latest.Connections, snapshot.Connections, snapshot.Time)...)
+		s.history.Add(detect.AlertEvents(s.latest.Alerts, snapshot.Alerts)...)
+	} else {
+		s.history.Add(detect.AlertEvents(nil, snapshot.Alerts)...)
+	}
+	s.latest = snapshot
+	s.lastErr = ""
+}
+
+func (s *Server) snapshot() (*models.Snapshot, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest, s.lastErr
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/snapshot", s.handleSnapshot)
+	mux.HandleFunc("/api/connections", s.handleConnections)
+	mux.HandleFunc("/api/processes", s.handleProcesses)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	return mux
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := s.requireSnapshot(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := s.requireSnapshot(w, r)
+	if !ok {
+		return
+	}
+
+	conns, err := queryConnections(snapshot.Connections, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if conns == nil {
+		conns = []models.ConnectionItem{}
+	}
+	writeJSON(w, http.StatusOK, conns)
+}
+
+func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := s.requireSnapshot(w, r)
+	if !ok {
+		return
+	}
+
+	conns, err := queryConnections(snapshot.Connections, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, summarizeProcesses(conns))
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid since: %v", err)})
+			return
+		}
+		since = parsed
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	events := s.history.Since(since, limit)
+	if events == nil {
+		events = []models.Event{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *Server) requireSnapshot(w http.ResponseWriter, r *http.Request) (*models.Snapshot, bool) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return nil, false
+	}
+
+	snapshot, lastErr := s.snapshot()
+	if snapshot == nil {
+		if lastErr == "" {
+			lastErr = "no snapshot collected yet"
+		}
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: lastErr})
+		return nil, false
+	}
+	return snapshot, true
+}
+
+func queryConnections(conns []models.ConnectionItem, r *http.Request) ([]models.ConnectionItem, error) {
+	q := r.URL.Query()
+
+	state := strings.ToLower(q.Get("state"))
+	if state == "" {
+		state = "all"
+	}
+	switch state {
+	case "all", "listening", "established":
+	default:
+		return nil, fmt.Errorf("invalid state %q (want all, listening or established)", state)
+	}
+
+	port := 0
+	if value := q.Get("port"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		port = parsed
+	}
+
+	proto := strings.ToUpper(q.Get("proto"))
+	process := q.Get("process")
+	pid := q.Get("pid")
+	user := q.Get("user")
+
+	var filtered []models.ConnectionItem
+	for _, conn := range connections.FilterConnections(conns, state) {
+		if proto != "" && !strings.HasPrefix(conn.Proto, proto) {
+			continue
+		}
+		if process != "" && conn.Process != process {
+			continue
+		}
+		if pid != "" && conn.PID != pid {
+			continue
+		}
+		if user != "" && conn.User != user && conn.UID != user {
+			continue
+		}
+		if port != 0 && conn.LocalPort != port && conn.RemotePort != port {
+			continue
+		}
+		filtered = append(filtered, conn)
+	}
+	return filtered, nil
+}
+
+func summarizeProcesses(conns []models.ConnectionItem) []ProcessSummary {
+	byPID := make(map[string]*ProcessSummary)
+	remotes := make(map[string]map[string]bool)
+
+	for _, conn := range conns {
+		summary, exists := byPID[conn.PID]
+		if !exists {
+			summary = &ProcessSummary{
+				PID:         conn.PID,
+				Process:     conn.Process,
+				User:        conn.User,
+				States:      make(map[string]int),
+				ListenPorts: []int{},
+				Remotes:     []string{},
+			}
+			byPID[conn.PID] = summary
+			remotes[conn.PID] = make(map[string]bool)
+		}
+
+		summary.Sockets++
+		summary.States[conn.State]++
+		if conn.IsListening() {
+			summary.ListenPorts = append(summary.ListenPorts, conn.LocalPort)
+		} else if conn.RemotePort != 0 && !remotes[conn.PID][conn.RemoteIP] {
+			remotes[conn.PID][conn.RemoteIP] = true
+			summary.Remotes = append(summary.Remotes, conn.RemoteIP)
+		}
+	}
+
+	summaries := make([]ProcessSummary, 0, len(byPID))
+	for _, summary := range byPID {
+		sort.Ints(summary.ListenPorts)
+		sort.Strings(summary.Remotes)
+		summaries = append(summaries, *summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Sockets != summaries[j].Sockets {
+			return summaries[i].Sockets > summaries[j].Sockets
+		}
+		return summaries[i].PID < summaries[j].PID
+	})
+	return summaries
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func Listen(addr string, mode os.FileMode) (net.Listener, error) {
+	if !strings.HasPrefix(addr, "unix:") {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, "unix:")
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		// Only a stale socket may be replaced: if something still accepts
+		// connections on it, another instance is running.
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another instance", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("probe %s: %v", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket %s: %v", path, err)
+		}
+	}
+
+	// Create the socket with no group/other access so nobody can connect
+	// before the requested mode is applied.
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("chmod %s: %v", path, err)
+	}
+
+	return listener, nil
+}
@@ -0,0 +1,172 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// Entry is one expected listening socket.
+type Entry struct {
+	Proto   string `json:"proto"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Process string `json:"process,omitempty"`
+	Exe     string `json:"exe,omitempty"`
+	User    string `json:"user,omitempty"`
+}
+
+func (e Entry) key() string {
+	return e.Proto + "|" + e.Address + "|" + strconv.Itoa(e.Port)
+}
+
+func (e Entry) String() string {
+	s := fmt.Sprintf("%s %s:%d", e.Proto, e.Address, e.Port)
+	if e.Process != "" {
+		s += " " + e.Process
+	}
+	if e.Exe != "" {
+		s += " (" + e.Exe + ")"
+	}
+	if e.User != "" {
+		s += " user " + e.User
+	}
+	return s
+}
+
+// ephemeral reports whether e looks like an unconnected UDP client socket:
+// bound to the wildcard address on a port the kernel picked from the local
+// port range. Those come and go with every DNS lookup and are not listeners.
+func (e Entry) ephemeral(ports PortRange) bool {
+	if !strings.HasPrefix(e.Proto, "UDP") || !ports.Contains(e.Port) {
+		return false
+	}
+	addr, err := netip.ParseAddr(e.Address)
+	return err == nil && addr.IsUnspecified()
+}
+
+// PortRange is the kernel's ip_local_port_range.
+type PortRange struct {
+	Low  int `json:"low"`
+	High int `json:"high"`
+}
+
+// DefaultPortRange is the kernel default, used when the range can't be read.
+var DefaultPortRange = PortRange{Low: 32768, High: 60999}
+
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Low && port <= r.High
+}
+
+// ReadPortRange reads ip_local_port_range from procRoot, falling back to
+// the kernel default.
+func ReadPortRange(procRoot string) PortRange {
+	data, err := os.ReadFile(filepath.Join(procRoot, "sys/net/ipv4/ip_local_port_range"))
+	if err != nil {
+		return DefaultPortRange
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return DefaultPortRange
+	}
+	low, err1 := strconv.Atoi(fields[0])
+	high, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || low > high {
+		return DefaultPortRange
+	}
+	return PortRange{Low: low, High: high}
+}
+
+type Baseline struct {
+	Host           string     `json:"host,omitempty"`
+	Created        time.Time  `json:"created"`
+	EphemeralPorts *PortRange `json:"ephemeral_ports,omitempty"`
+	Listeners      []Entry    `json:"listeners"`
+}
+
+func (b Baseline) ephemeralPorts() PortRange {
+	if b.EphemeralPorts != nil {
+		return *b.EphemeralPorts
+	}
+	return DefaultPortRange
+}
+
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "stattui", "baseline.json")
+}
+
+func entryOf(conn models.ConnectionItem) Entry {
+	return Entry{
+		Proto:   conn.Proto,
+		Address: conn.LocalIP,
+		Port:    conn.LocalPort,
+		Process: conn.Process,
+		Exe:     conn.Exe,
+		User:    conn.User,
+	}
+}
+
+func FromSnapshot(snapshot *models.Snapshot, ports PortRange) Baseline {
+	b := Baseline{Host: snapshot.Host, Created: snapshot.Time, EphemeralPorts: &ports}
+
+	seen := make(map[string]bool)
+	for _, conn := range snapshot.Connections {
+		if !conn.IsListening() {
+			continue
+		}
+		entry := entryOf(conn)
+		if entry.ephemeral(ports) || seen[entry.key()] {
+			continue
+		}
+		seen[entry.key()] = true
+		b.Listeners = append(b.Listeners, entry)
+	}
+
+	sort.Slice(b.Listeners, func(i, j int) bool {
+		a, c := b.Listeners[i], b.Listeners[j]
+		if a.Port != c.Port {
+			return a.Port < c.Port
+		}
+		return a.key() < c.key()
+	})
+	return b
+}
+
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	return &b, nil
+}
+
+func (b Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save baseline: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save baseline: %v", err)
+	}
+	return nil
+}
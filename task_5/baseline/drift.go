@@ -0,0 +1,134 @@
+package baseline
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type DriftKind string
+
+const (
+	DriftNew     DriftKind = "new"
+	DriftMissing DriftKind = "missing"
+	DriftChanged DriftKind = "changed"
+)
+
+type Drift struct {
+	Kind       DriftKind
+	Expected   *Entry
+	Actual     *Entry
+	Connection *models.ConnectionItem
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftNew:
+		return "new listener " + d.Actual.String()
+	case DriftMissing:
+		return "missing listener " + d.Expected.String()
+	default:
+		return fmt.Sprintf("%s %s:%d now owned by %s, expected %s",
+			d.Actual.Proto, d.Actual.Address, d.Actual.Port, owner(*d.Actual), owner(*d.Expected))
+	}
+}
+
+func (d Drift) Severity() string {
+	if d.Kind == DriftChanged {
+		return models.SeverityCritical
+	}
+	return models.SeverityWarning
+}
+
+func owner(e Entry) string {
+	if e.Exe != "" {
+		return e.Exe
+	}
+	if e.Process != "" {
+		return e.Process
+	}
+	return "unknown"
+}
+
+// sameBinary reports whether two entries were opened by the same program.
+// Owners the collector could not resolve are given the benefit of the doubt.
+func sameBinary(expected, actual Entry) bool {
+	if expected.Exe != "" && actual.Exe != "" {
+		return expected.Exe == actual.Exe
+	}
+	if expected.Process != "" && actual.Process != "" {
+		return expected.Process == actual.Process
+	}
+	return true
+}
+
+// Compare lists the differences between the expected listeners and the
+// LISTEN sockets in connections. Connections from other hosts than the one
+// the baseline was taken on, and ephemeral UDP client sockets, are ignored.
+func (b Baseline) Compare(connections []models.ConnectionItem) []Drift {
+	ports := b.ephemeralPorts()
+	expected := make(map[string]Entry, len(b.Listeners))
+	for _, entry := range b.Listeners {
+		if !entry.ephemeral(ports) {
+			expected[entry.key()] = entry
+		}
+	}
+
+	var drifts []Drift
+	seen := make(map[string]bool)
+	for _, conn := range connections {
+		if !conn.IsListening() || (conn.Host != "" && b.Host != "" && conn.Host != b.Host) {
+			continue
+		}
+		actual := entryOf(conn)
+		if actual.ephemeral(ports) || seen[actual.key()] {
+			continue
+		}
+		seen[actual.key()] = true
+
+		listener := conn
+		want, known := expected[actual.key()]
+		switch {
+		case !known:
+			drifts = append(drifts, Drift{Kind: DriftNew, Actual: &actual, Connection: &listener})
+		case !sameBinary(want, actual):
+			drifts = append(drifts, Drift{Kind: DriftChanged, Expected: &want, Actual: &actual, Connection: &listener})
+		}
+	}
+
+	for _, entry := range b.Listeners {
+		if _, ok := expected[entry.key()]; ok && !seen[entry.key()] {
+			missing := entry
+			drifts = append(drifts, Drift{Kind: DriftMissing, Expected: &missing})
+		}
+	}
+	return drifts
+}
+
+// Monitor reports drift from a baseline as alerts on every snapshot.
+type Monitor struct {
+	baseline *Baseline
+}
+
+func NewMonitor(b *Baseline) *Monitor {
+	return &Monitor{baseline: b}
+}
+
+func (m *Monitor) Observe(snapshot *models.Snapshot) []models.Alert {
+	var alerts []models.Alert
+	for _, drift := range m.baseline.Compare(snapshot.Connections) {
+		entry := drift.Actual
+		if entry == nil {
+			entry = drift.Expected
+		}
+		alerts = append(alerts, models.Alert{
+			Time:       snapshot.Time,
+			Severity:   drift.Severity(),
+			Source:     "baseline",
+			Key:        "baseline|" + string(drift.Kind) + "|" + entry.key(),
+			Message:    drift.String(),
+			Connection: drift.Connection,
+		})
+	}
+	return alerts
+}
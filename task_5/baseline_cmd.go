@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mizerael/infsec_ssu/task_5/baseline"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+)
+
+// runBaseline records or checks the expected set of listening sockets.
+// check exits 1 when it finds drift and 2 when it cannot run, like diff(1).
+func runBaseline(args []string) {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	file := fs.String("file", baseline.DefaultPath(), "baseline file")
+	procRoot := fs.String("proc", "/proc", "procfs mount to read sockets and processes from")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s baseline save|check [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	snapshot, err := connections.NewCollector(*procRoot).Collect()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	switch action {
+	case "save":
+		b := baseline.FromSnapshot(snapshot, baseline.ReadPortRange(*procRoot))
+		if err := b.Save(*file); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Printf("Saved %d listeners to %s\n", len(b.Listeners), *file)
+
+	case "check":
+		b, err := baseline.Load(*file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		drifts := b.Compare(snapshot.Connections)
+		for _, drift := range drifts {
+			fmt.Printf("%-8s %s\n", drift.Severity(), drift)
+		}
+		if len(drifts) > 0 {
+			fmt.Printf("%d differences from %s\n", len(drifts), *file)
+			os.Exit(1)
+		}
+		fmt.Printf("No drift from %s (%d listeners)\n", *file, len(b.Listeners))
+
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mizerael/infsec_ssu/task_5/detect"
+	"github.com/mizerael/infsec_ssu/task_5/resolve"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
+)
+
+type Config struct {
+	Theme         string                 `json:"theme"`
+	Themes        map[string]theme.Theme `json:"themes"`
+	Backlog       detect.BacklogOptions  `json:"backlog"`
+	Scan          detect.ScanOptions     `json:"scan"`
+	Rules         string                 `json:"rules"`
+	Baseline      string                 `json:"baseline"`
+	Blocklists    []string               `json:"blocklists"`
+	GeoIP         []string               `json:"geoip"`
+	Resolve       resolve.Options        `json:"resolve"`
+	CrossCheck    bool                   `json:"crosscheck"`
+	Conntrack     bool                   `json:"conntrack"`
+	ReadOnly      bool                   `json:"read_only"`
+	DryRun        bool                   `json:"dry_run"`
+	ApplyFirewall bool                   `json:"apply_firewall"`
+}
+
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "stattui", "config.json")
+}
+
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+		if path == "" {
+			return cfg, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("config error: %v", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("config error: %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
@@ -2,76 +2,190 @@ package connections
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/procnet"
+	"github.com/mizerael/infsec_ssu/task_5/services"
+	"golang.org/x/sys/unix"
 )
 
+type Collector struct {
+	ProcRoot string
+
+	hashes *exeHasher
+}
+
+var defaultCollector = NewCollector("/proc")
+
+func NewCollector(procRoot string) *Collector {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	return &Collector{ProcRoot: procRoot, hashes: newExeHasher()}
+}
+
+func Default() *Collector {
+	return defaultCollector
+}
+
 func GetConnections(filterState string) ([]models.ConnectionItem, error) {
-	connections, err := readAllConnections()
+	snapshot, err := defaultCollector.Collect()
 	if err != nil {
 		return nil, err
 	}
-	return filterConnections(connections, filterState), nil
+	return filterConnections(snapshot.Connections, filterState), nil
 }
 
-func readAllConnections() ([]models.ConnectionItem, error) {
-	var connections []models.ConnectionItem
-	var errors []string
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	started := time.Now()
 
-	tcpConnections, err := readTCPConnections()
+	connections, errors := c.readAllConnections()
+	connections, stats := c.enrichWithProcessInfo(connections)
+	c.enrichWithTCPInfo(connections)
+	enrichWithServices(connections, services.Default())
+
+	sockstat, err := procnet.ReadSockstat(c.ProcRoot)
 	if err != nil {
-		errors = append(errors, fmt.Sprintf("TCP: %v", err))
-	} else {
-		connections = append(connections, tcpConnections...)
+		errors = append(errors, err.Error())
 	}
 
-	udpConnections, err := readUDPConnections()
+	counters, err := procnet.ReadCounters(c.ProcRoot)
 	if err != nil {
-		errors = append(errors, fmt.Sprintf("UDP: %v", err))
-	} else {
-		connections = append(connections, udpConnections...)
+		errors = append(errors, err.Error())
 	}
 
-	connections = enrichWithProcessInfo(connections)
+	snapshot := &models.Snapshot{
+		Host:        c.Hostname(),
+		Time:        started,
+		Connections: connections,
+		Ownership:   stats,
+		Errors:      errors,
+		Duration:    time.Since(started),
+		Sockstat:    sockstat,
+		Counters:    counters,
+	}
 
 	if len(errors) > 0 && len(connections) == 0 {
-		return nil, fmt.Errorf("failed to read connections: %s", strings.Join(errors, "; "))
+		return snapshot, fmt.Errorf("failed to read connections: %s", strings.Join(errors, "; "))
 	}
 
-	return connections, nil
+	return snapshot, nil
 }
 
-func readTCPConnections() ([]models.ConnectionItem, error) {
+// enrichWithTCPInfo attaches tcp_info from inet_diag. Netlink always reports
+// the running kernel, so it is skipped when reading a fixture procfs.
+func (c *Collector) enrichWithTCPInfo(connections []models.ConnectionItem) {
+	if c.ProcRoot != "/proc" || len(connections) == 0 {
+		return
+	}
+
+	sockets, err := QueryInetDiag(unix.IPPROTO_TCP)
+	if err != nil {
+		return
+	}
+
+	byInode := make(map[string]DiagSocket, len(sockets))
+	for _, socket := range sockets {
+		if socket.Inode != "0" {
+			byInode[socket.Inode] = socket
+		}
+	}
+
+	for i := range connections {
+		socket, exists := byInode[connections[i].Inode]
+		if !exists {
+			continue
+		}
+		if socket.TCP != nil {
+			connections[i].TCP = socket.TCP
+		}
+		// /proc/net/tcp leaves tx_queue at zero for listeners; inet_diag
+		// reports the backlog limit there instead.
+		if connections[i].IsListening() {
+			connections[i].RxQueue = int(socket.RxQueue)
+			connections[i].TxQueue = int(socket.TxQueue)
+		}
+	}
+}
+
+func enrichWithServices(connections []models.ConnectionItem, db *services.DB) {
+	for i := range connections {
+		conn := &connections[i]
+		if s, ok := db.Lookup(conn.Proto, conn.LocalPort); ok && conn.LocalPort != 0 {
+			conn.LocalService = s.Name
+		}
+		if s, ok := db.Lookup(conn.Proto, conn.RemotePort); ok && conn.RemotePort != 0 {
+			conn.RemoteService = s.Name
+		}
+	}
+}
+
+func (c *Collector) Hostname() string {
+	if data, err := os.ReadFile(filepath.Join(c.ProcRoot, "sys", "kernel", "hostname")); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "localhost"
+}
+
+func (c *Collector) readAllConnections() ([]models.ConnectionItem, []string) {
 	var connections []models.ConnectionItem
+	var errors []string
 
-	if tcp4, err := readProcNetFile("/proc/net/tcp", "TCP"); err == nil {
-		connections = append(connections, tcp4...)
+	tcpConnections, err := c.readTCPConnections()
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("TCP: %v", err))
+	} else {
+		connections = append(connections, tcpConnections...)
 	}
 
-	if tcp6, err := readProcNetFile("/proc/net/tcp6", "TCP6"); err == nil {
-		connections = append(connections, tcp6...)
+	udpConnections, err := c.readUDPConnections()
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("UDP: %v", err))
+	} else {
+		connections = append(connections, udpConnections...)
 	}
 
-	return connections, nil
+	return connections, errors
+}
+
+func (c *Collector) readTCPConnections() ([]models.ConnectionItem, error) {
+	return c.readProtoFiles("tcp", "TCP")
+}
+
+func (c *Collector) readUDPConnections() ([]models.ConnectionItem, error) {
+	return c.readProtoFiles("udp", "UDP")
 }
 
-func readUDPConnections() ([]models.ConnectionItem, error) {
+func (c *Collector) readProtoFiles(name, proto string) ([]models.ConnectionItem, error) {
 	var connections []models.ConnectionItem
 
-	if udp4, err := readProcNetFile("/proc/net/udp", "UDP"); err == nil {
-		connections = append(connections, udp4...)
+	v4, err4 := readProcNetFile(filepath.Join(c.ProcRoot, "net", name), proto)
+	if err4 == nil {
+		connections = append(connections, v4...)
+	}
+
+	v6, err6 := readProcNetFile(filepath.Join(c.ProcRoot, "net", name+"6"), proto+"6")
+	if err6 == nil {
+		connections = append(connections, v6...)
 	}
 
-	if udp6, err := readProcNetFile("/proc/net/udp6", "UDP6"); err == nil {
-		connections = append(connections, udp6...)
+	if err4 != nil && err6 != nil {
+		return nil, err4
 	}
 
 	return connections, nil
@@ -129,6 +243,8 @@ func parseConnectionLine(line, proto string) (*models.ConnectionItem, error) {
 	localAddr := fields[1]
 	remoteAddr := fields[2]
 	state := fields[3]
+	queues := fields[4]
+	uid := fields[7]
 	inode := fields[9]
 
 	localIP, localPort, err := parseHexIPPort(localAddr)
@@ -144,16 +260,41 @@ func parseConnectionLine(line, proto string) (*models.ConnectionItem, error) {
 	stateName := getTCPStateName(state, proto)
 	local := formatAddress(localIP, localPort)
 	remote := formatAddress(remoteIP, remotePort)
+	localPortNum, _ := strconv.Atoi(localPort)
+	remotePortNum, _ := strconv.Atoi(remotePort)
+	txQueue, rxQueue := parseQueues(queues)
 
 	return &models.ConnectionItem{
-		Proto:  proto,
-		Local:  local,
-		Remote: remote,
-		State:  stateName,
-		PID:    inode,
+		Proto:      proto,
+		Local:      local,
+		Remote:     remote,
+		State:      stateName,
+		PID:        inode,
+		LocalIP:    localIP,
+		LocalPort:  localPortNum,
+		RemoteIP:   remoteIP,
+		RemotePort: remotePortNum,
+		Inode:      inode,
+		UID:        uid,
+		User:       lookupUser(uid),
+		TxQueue:    txQueue,
+		RxQueue:    rxQueue,
 	}, nil
 }
 
+// parseQueues splits the "tx_queue:rx_queue" column. For LISTEN sockets the
+// kernel reports the accept queue depth as rx_queue; the backlog limit only
+// comes from inet_diag.
+func parseQueues(field string) (int, int) {
+	txHex, rxHex, ok := strings.Cut(field, ":")
+	if !ok {
+		return 0, 0
+	}
+	tx, _ := strconv.ParseInt(txHex, 16, 64)
+	rx, _ := strconv.ParseInt(rxHex, 16, 64)
+	return int(tx), int(rx)
+}
+
 func parseHexIPPort(hexAddr string) (string, string, error) {
 	parts := strings.Split(hexAddr, ":")
 	if len(parts) != 2 {
@@ -195,9 +336,10 @@ func parseHexIP(hexIP string) (string, error) {
 	}
 
 	if len(hexIP) == 32 {
+		// The kernel prints the address as four host-order 32-bit words.
 		ipBytes := make([]byte, 16)
 		for i := 0; i < 16; i++ {
-			start := (15 - i) * 2
+			start := (i/4*4 + 3 - i%4) * 2
 			b, err := strconv.ParseInt(hexIP[start:start+2], 16, 16)
 			if err != nil {
 				return "", fmt.Errorf("error parsing IP byte: %v", err)
@@ -230,7 +372,17 @@ func getTCPStateName(stateHex, proto string) string {
 
 	states := map[int64]string{
 		1:  "ESTABLISHED",
+		2:  "SYN_SENT",
+		3:  "SYN_RECV",
+		4:  "FIN_WAIT1",
+		5:  "FIN_WAIT2",
+		6:  "TIME_WAIT",
+		7:  "CLOSE",
+		8:  "CLOSE_WAIT",
+		9:  "LAST_ACK",
 		10: "LISTEN",
+		11: "CLOSING",
+		12: "NEW_SYN_RECV",
 	}
 
 	if name, exists := states[state]; exists {
@@ -246,32 +398,72 @@ func formatAddress(ip, port string) string {
 	return ip + ":" + port
 }
 
-func enrichWithProcessInfo(connections []models.ConnectionItem) []models.ConnectionItem {
+var (
+	userCache   = make(map[string]string)
+	userCacheMu sync.Mutex
+)
+
+func lookupUser(uid string) string {
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+
+	if name, exists := userCache[uid]; exists {
+		return name
+	}
+
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	userCache[uid] = name
+	return name
+}
+
+func (c *Collector) enrichWithProcessInfo(connections []models.ConnectionItem) ([]models.ConnectionItem, models.OwnershipStats) {
 	if len(connections) == 0 {
-		return connections
+		return connections, models.OwnershipStats{}
 	}
 
-	inodeToPID := buildInodeToPIDMap()
+	inodeToPID, stdio, stats := c.buildInodeToPIDMap()
+	exes := make(map[string]exeInfo)
 
 	for i := range connections {
-		inode := connections[i].PID
+		inode := connections[i].Inode
 		if pid, exists := inodeToPID[inode]; exists {
 			connections[i].PID = pid
-			connections[i].Process = getProcessName(pid)
+			connections[i].Process = c.getProcessName(pid)
+			exe, seen := exes[pid]
+			if !seen {
+				exe = c.inspectExe(pid)
+				exes[pid] = exe
+			}
+			exe.apply(&connections[i])
+			for _, ref := range stdio[inode] {
+				ref.Process = c.getProcessName(ref.PID)
+				connections[i].Stdio = append(connections[i].Stdio, ref)
+			}
+			stats.Resolved++
 		} else {
 			connections[i].PID = "N/A"
+			stats.Unresolved++
 		}
 	}
 
-	return connections
+	return connections, stats
 }
 
-func buildInodeToPIDMap() map[string]string {
+// buildInodeToPIDMap scans every process's fds for sockets. Besides the
+// owner of each inode it notes which sockets sit on fds 0-2, since a
+// process talking to the network through its stdio is how most reverse
+// shells look.
+func (c *Collector) buildInodeToPIDMap() (map[string]string, map[string][]models.StdioFD, models.OwnershipStats) {
 	inodeToPID := make(map[string]string)
+	stdio := make(map[string][]models.StdioFD)
+	var stats models.OwnershipStats
 
-	procDirs, err := filepath.Glob("/proc/[0-9]*")
+	procDirs, err := filepath.Glob(filepath.Join(c.ProcRoot, "[0-9]*"))
 	if err != nil {
-		return inodeToPID
+		return inodeToPID, stdio, stats
 	}
 
 	var wg sync.WaitGroup
@@ -290,6 +482,16 @@ func buildInodeToPIDMap() map[string]string {
 
 			fdDir := filepath.Join(procDir, "fd")
 			fds, err := os.ReadDir(fdDir)
+
+			mutex.Lock()
+			stats.ProcessesScanned++
+			// Processes that exit mid-scan fail with ENOENT; only a
+			// permission error means sockets may be hidden from us.
+			if errors.Is(err, fs.ErrPermission) {
+				stats.ProcessesDenied++
+			}
+			mutex.Unlock()
+
 			if err != nil {
 				return
 			}
@@ -307,6 +509,10 @@ func buildInodeToPIDMap() map[string]string {
 
 					mutex.Lock()
 					inodeToPID[inode] = pid
+					stats.SocketFDs++
+					if n, err := strconv.Atoi(fd.Name()); err == nil && n <= 2 {
+						stdio[inode] = append(stdio[inode], models.StdioFD{PID: pid, FD: n})
+					}
 					mutex.Unlock()
 				}
 			}
@@ -314,15 +520,15 @@ func buildInodeToPIDMap() map[string]string {
 	}
 
 	wg.Wait()
-	return inodeToPID
+	return inodeToPID, stdio, stats
 }
 
-func getProcessName(pid string) string {
-	if data, err := os.ReadFile(filepath.Join("/proc", pid, "comm")); err == nil {
+func (c *Collector) getProcessName(pid string) string {
+	if data, err := os.ReadFile(filepath.Join(c.ProcRoot, pid, "comm")); err == nil {
 		return strings.TrimSpace(string(data))
 	}
 
-	if data, err := os.ReadFile(filepath.Join("/proc", pid, "cmdline")); err == nil {
+	if data, err := os.ReadFile(filepath.Join(c.ProcRoot, pid, "cmdline")); err == nil {
 		cmdline := strings.TrimSpace(string(data))
 		if idx := strings.IndexAny(cmdline, "\x00 "); idx != -1 {
 			cmdline = cmdline[:idx]
@@ -0,0 +1,299 @@
+package connections
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"golang.org/x/sys/unix"
+)
+
+// crossCheckPass is what each source said about sockets at one moment.
+type crossCheckPass struct {
+	proc       map[string]models.ConnectionItem
+	diag       map[string]DiagSocket
+	diagProtos map[string]bool
+	other      map[string]string
+	fds        map[string]string
+}
+
+var (
+	// otherInetTables share the tcp/udp layout but are not collected for
+	// the connection list.
+	otherInetTables = map[string]string{
+		"raw": "RAW", "raw6": "RAW6", "udplite": "UDPLITE", "udplite6": "UDPLITE6", "icmp": "ICMP", "icmp6": "ICMP6",
+	}
+	// socketTables cover the non-inet families a process may hold.
+	socketTables = []string{"unix", "netlink", "packet"}
+	// tabledProtocols are the sockprotoname values of sockets that show up
+	// in one of the tables above. Others, such as SCTP, vsock or AF_ALG,
+	// have no table to be missing from.
+	tabledProtocols = map[string]bool{
+		"TCP": true, "TCPv6": true, "UDP": true, "UDPv6": true, "UDP-Lite": true, "UDPLITEv6": true,
+		"RAW": true, "RAWv6": true, "PING": true, "PINGv6": true, "NETLINK": true, "PACKET": true,
+		"UNIX": true, "UNIX-STREAM": true, "UNIX-DGRAM": true, "UNIX-SEQPACKET": true,
+	}
+)
+
+// CrossCheck compares three views of the socket tables: the /proc/net text
+// files, inet_diag over netlink and the socket fds found by scanning
+// processes. A rootkit that filters one of them leaves the others behind,
+// so every disagreement is returned as a finding. Sockets open and close
+// between reads, so callers should only trust findings that repeat.
+func (c *Collector) CrossCheck() ([]models.Alert, error) {
+	if c.ProcRoot != "/proc" {
+		return nil, fmt.Errorf("cross-check needs the local /proc, not %s", c.ProcRoot)
+	}
+
+	pass, err := c.crossCheckPass()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var findings []models.Alert
+	for inode, socket := range pass.diag {
+		if _, ok := pass.proc[inode]; ok {
+			continue
+		}
+		conn := diagConnection(socket)
+		findings = append(findings, models.Alert{
+			Time:     now,
+			Severity: models.SeverityCritical,
+			Source:   "crosscheck",
+			Key:      "crosscheck|diag|" + inode,
+			Message: fmt.Sprintf("%s %s → %s (inode %s) is reported by inet_diag but hidden from /proc/net/%s",
+				conn.Proto, conn.Local, conn.Remote, inode, strings.ToLower(conn.Proto)),
+			Connection: &conn,
+		})
+	}
+
+	for inode, conn := range pass.proc {
+		if _, ok := pass.diag[inode]; ok || !pass.diagProtos[conn.Proto] {
+			continue
+		}
+		listed := conn
+		findings = append(findings, models.Alert{
+			Time:     now,
+			Severity: models.SeverityWarning,
+			Source:   "crosscheck",
+			Key:      "crosscheck|proc|" + inode,
+			Message: fmt.Sprintf("%s %s → %s (inode %s) is in /proc/net but missing from inet_diag",
+				conn.Proto, conn.Local, conn.Remote, inode),
+			Connection: &listed,
+		})
+	}
+
+	for inode, pid := range pass.fds {
+		if _, ok := pass.proc[inode]; ok {
+			continue
+		}
+		if _, ok := pass.diag[inode]; ok {
+			continue
+		}
+		if _, ok := pass.other[inode]; ok {
+			continue
+		}
+		protocol, known := c.socketProtocol(pid, inode)
+		if known && !tabledProtocols[protocol] {
+			continue
+		}
+		if protocol == "" {
+			protocol = "socket"
+		}
+		findings = append(findings, models.Alert{
+			Time:     now,
+			Severity: models.SeverityWarning,
+			Source:   "crosscheck",
+			Key:      "crosscheck|fd|" + inode,
+			Message: fmt.Sprintf("PID %s (%s) holds %s inode %s that no /proc/net table lists",
+				pid, c.getProcessName(pid), protocol, inode),
+		})
+	}
+
+	return findings, nil
+}
+
+// socketProtocol finds pid's fd for the socket inode and asks sockfs which
+// protocol it belongs to. It reports false if the fd is gone or the kernel
+// does not expose the name.
+func (c *Collector) socketProtocol(pid, inode string) (string, bool) {
+	fdDir := filepath.Join(c.ProcRoot, pid, "fd")
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return "", false
+	}
+	target := "socket:[" + inode + "]"
+	for _, entry := range entries {
+		path := filepath.Join(fdDir, entry.Name())
+		if link, err := os.Readlink(path); err != nil || link != target {
+			continue
+		}
+		buf := make([]byte, 64)
+		n, err := unix.Getxattr(path, "system.sockprotoname", buf)
+		if err != nil || n <= 0 {
+			return "", false
+		}
+		return strings.TrimRight(string(buf[:n]), "\x00"), true
+	}
+	return "", false
+}
+
+func (c *Collector) crossCheckPass() (crossCheckPass, error) {
+	pass := crossCheckPass{
+		proc:       make(map[string]models.ConnectionItem),
+		diag:       make(map[string]DiagSocket),
+		diagProtos: make(map[string]bool),
+		other:      make(map[string]string),
+		fds:        make(map[string]string),
+	}
+
+	// Socket fds are read first: anything opened after this point cannot
+	// be mistaken for an fd missing from the tables.
+	inodeToPID, _, _ := c.buildInodeToPIDMap()
+	self, _ := os.Readlink(filepath.Join(c.ProcRoot, "self", "ns", "net"))
+	namespaces := make(map[string]string)
+	for inode, pid := range inodeToPID {
+		ns, seen := namespaces[pid]
+		if !seen {
+			ns, _ = os.Readlink(filepath.Join(c.ProcRoot, pid, "ns", "net"))
+			namespaces[pid] = ns
+		}
+		// Sockets in other network namespaces live in tables we cannot see.
+		if ns == self {
+			pass.fds[inode] = pid
+		}
+	}
+
+	connections, errors := c.readAllConnections()
+	if len(connections) == 0 && len(errors) > 0 {
+		return pass, fmt.Errorf("failed to read connections: %s", strings.Join(errors, "; "))
+	}
+	for _, conn := range connections {
+		if conn.Inode != "0" {
+			pass.proc[conn.Inode] = conn
+		}
+	}
+
+	tcp, err := QueryInetDiag(unix.IPPROTO_TCP)
+	if err != nil {
+		return pass, err
+	}
+	// udp_diag is a separate module and may not be loaded.
+	udp, _ := QueryInetDiag(unix.IPPROTO_UDP)
+	for _, socket := range append(tcp, udp...) {
+		if socket.Inode != "0" {
+			pass.diag[socket.Inode] = socket
+		}
+		pass.diagProtos[socket.Proto] = true
+	}
+
+	for name, proto := range otherInetTables {
+		conns, err := readProcNetFile(filepath.Join(c.ProcRoot, "net", name), proto)
+		if err != nil {
+			continue
+		}
+		for _, conn := range conns {
+			pass.other[conn.Inode] = name
+		}
+	}
+	for _, name := range socketTables {
+		inodes, err := readInodeColumn(filepath.Join(c.ProcRoot, "net", name))
+		if err != nil {
+			continue
+		}
+		for _, inode := range inodes {
+			pass.other[inode] = name
+		}
+	}
+
+	return pass, nil
+}
+
+// readInodeColumn pulls the Inode column out of tables such as
+// /proc/net/unix whose header names line up with the data columns.
+func readInodeColumn(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	column := -1
+	for i, name := range strings.Fields(scanner.Text()) {
+		if strings.EqualFold(name, "inode") {
+			column = i
+		}
+	}
+	if column < 0 {
+		return nil, fmt.Errorf("%s has no inode column", path)
+	}
+
+	var inodes []string
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if column < len(fields) {
+			inodes = append(inodes, fields[column])
+		}
+	}
+	return inodes, scanner.Err()
+}
+
+func diagConnection(socket DiagSocket) models.ConnectionItem {
+	return models.ConnectionItem{
+		Proto:      socket.Proto,
+		Local:      formatAddress(socket.LocalIP, fmt.Sprint(socket.LocalPort)),
+		Remote:     formatAddress(socket.RemoteIP, fmt.Sprint(socket.RemotePort)),
+		LocalIP:    socket.LocalIP,
+		LocalPort:  socket.LocalPort,
+		RemoteIP:   socket.RemoteIP,
+		RemotePort: socket.RemotePort,
+		Inode:      socket.Inode,
+		UID:        fmt.Sprint(socket.UID),
+		User:       lookupUser(fmt.Sprint(socket.UID)),
+	}
+}
+
+// CrossChecker runs CrossCheck on every snapshot and reports only findings
+// that were also present on the previous one, which filters out sockets
+// that merely opened or closed between reads.
+type CrossChecker struct {
+	collector *Collector
+	previous  map[string]bool
+}
+
+func NewCrossChecker(c *Collector) *CrossChecker {
+	return &CrossChecker{collector: c}
+}
+
+func (x *CrossChecker) Observe(snapshot *models.Snapshot) []models.Alert {
+	findings, err := x.collector.CrossCheck()
+	if err != nil {
+		return []models.Alert{{
+			Time:     snapshot.Time,
+			Severity: models.SeverityInfo,
+			Source:   "crosscheck",
+			Key:      "crosscheck|error",
+			Message:  "cross-check unavailable: " + err.Error(),
+		}}
+	}
+
+	current := make(map[string]bool, len(findings))
+	var confirmed []models.Alert
+	for _, finding := range findings {
+		current[finding.Key] = true
+		if x.previous[finding.Key] {
+			confirmed = append(confirmed, finding)
+		}
+	}
+	x.previous = current
+	return confirmed
+}
@@ -0,0 +1,110 @@
+//go:build linux
+
+package connections
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"golang.org/x/sys/unix"
+)
+
+// DestroySocket closes a single TCP socket with SOCK_DESTROY, the same
+// operation `ss -K` uses. The peer sees a reset and the owning process
+// gets ECONNABORTED on its next call; nothing else about it is touched.
+func DestroySocket(conn models.ConnectionItem) error {
+	if !strings.HasPrefix(conn.Proto, "TCP") {
+		return fmt.Errorf("only TCP sockets can be destroyed, not %s", conn.Proto)
+	}
+
+	family := uint8(unix.AF_INET)
+	if conn.Proto == "TCP6" {
+		family = unix.AF_INET6
+	}
+	src, err := diagAddrBytes(family, conn.LocalIP)
+	if err != nil {
+		return err
+	}
+	dst, err := diagAddrBytes(family, conn.RemoteIP)
+	if err != nil {
+		return err
+	}
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return fmt.Errorf("sock_diag socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	req := make([]byte, unix.SizeofNlMsghdr+sizeofInetDiagReq)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], unix.SOCK_DESTROY)
+	binary.NativeEndian.PutUint16(req[6:8], unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+
+	body := req[unix.SizeofNlMsghdr:]
+	body[0] = family
+	body[1] = unix.IPPROTO_TCP
+	binary.NativeEndian.PutUint32(body[4:8], allStates)
+
+	id := body[8:]
+	binary.BigEndian.PutUint16(id[0:2], uint16(conn.LocalPort))
+	binary.BigEndian.PutUint16(id[2:4], uint16(conn.RemotePort))
+	copy(id[4:20], src)
+	copy(id[20:36], dst)
+	// No cookie: the kernel looks the socket up by its four-tuple alone.
+	binary.NativeEndian.PutUint32(id[40:44], 0xffffffff)
+	binary.NativeEndian.PutUint32(id[44:48], 0xffffffff)
+
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("sock_diag send: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	n, _, err := unix.Recvfrom(fd, buf, 0)
+	if err != nil {
+		return fmt.Errorf("sock_diag recv: %v", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+	if err != nil {
+		return fmt.Errorf("sock_diag parse: %v", err)
+	}
+
+	for _, msg := range msgs {
+		if msg.Header.Type != unix.NLMSG_ERROR || len(msg.Data) < 4 {
+			continue
+		}
+		if errno := int32(binary.NativeEndian.Uint32(msg.Data[0:4])); errno != 0 {
+			return destroyError(unix.Errno(-errno))
+		}
+	}
+	return nil
+}
+
+func diagAddrBytes(family uint8, addr string) ([]byte, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", addr)
+	}
+	if family == unix.AF_INET {
+		return ip.To4(), nil
+	}
+	return ip.To16(), nil
+}
+
+func destroyError(errno unix.Errno) error {
+	switch {
+	case errors.Is(errno, unix.EPERM), errors.Is(errno, unix.EACCES):
+		return fmt.Errorf("permission denied: destroying sockets needs root or CAP_NET_ADMIN")
+	case errors.Is(errno, unix.EOPNOTSUPP):
+		return fmt.Errorf("kernel does not support SOCK_DESTROY (CONFIG_INET_DIAG_DESTROY)")
+	case errors.Is(errno, unix.ENOENT):
+		return fmt.Errorf("socket no longer exists")
+	}
+	return fmt.Errorf("sock_destroy: %v", errno)
+}
@@ -0,0 +1,124 @@
+package connections
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+func Diff(prev, next []models.ConnectionItem) (opened, closed []models.ConnectionItem) {
+	before := make(map[string]bool, len(prev))
+	for _, conn := range prev {
+		before[conn.Key()] = true
+	}
+
+	after := make(map[string]bool, len(next))
+	for _, conn := range next {
+		after[conn.Key()] = true
+		if !before[conn.Key()] {
+			opened = append(opened, conn)
+		}
+	}
+
+	for _, conn := range prev {
+		if !after[conn.Key()] {
+			closed = append(closed, conn)
+		}
+	}
+
+	return opened, closed
+}
+
+func DiffEvents(prev, next []models.ConnectionItem, at time.Time) []models.Event {
+	opened, closed := Diff(prev, next)
+
+	events := make([]models.Event, 0, len(opened)+len(closed))
+	for _, conn := range opened {
+		events = append(events, models.Event{Time: at, Kind: models.EventOpened, Connection: conn})
+	}
+	for _, conn := range closed {
+		events = append(events, models.Event{Time: at, Kind: models.EventClosed, Connection: conn})
+	}
+	return events
+}
+
+func FilterConnections(connections []models.ConnectionItem, filterState string) []models.ConnectionItem {
+	return filterConnections(connections, filterState)
+}
+
+func DiffSnapshots(prev, next *models.Snapshot) models.SnapshotDiff {
+	diff := models.SnapshotDiff{
+		Host:      next.Host,
+		Time:      next.Time,
+		Ownership: next.Ownership,
+		Errors:    next.Errors,
+		Duration:  next.Duration,
+		Sockstat:  next.Sockstat,
+		Counters:  next.Counters,
+	}
+
+	before := make(map[string]models.ConnectionItem, len(prev.Connections))
+	for _, conn := range prev.Connections {
+		before[conn.Key()] = conn
+	}
+
+	seen := make(map[string]bool, len(next.Connections))
+	for _, conn := range next.Connections {
+		key := conn.Key()
+		seen[key] = true
+
+		old, existed := before[key]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, conn)
+		case !reflect.DeepEqual(old, conn):
+			diff.Updated = append(diff.Updated, conn)
+		}
+	}
+
+	for _, conn := range prev.Connections {
+		if !seen[conn.Key()] {
+			diff.Removed = append(diff.Removed, conn.Key())
+		}
+	}
+
+	return diff
+}
+
+func ApplyDiff(base *models.Snapshot, diff models.SnapshotDiff) *models.Snapshot {
+	removed := make(map[string]bool, len(diff.Removed))
+	for _, key := range diff.Removed {
+		removed[key] = true
+	}
+
+	updated := make(map[string]models.ConnectionItem, len(diff.Updated))
+	for _, conn := range diff.Updated {
+		updated[conn.Key()] = conn
+	}
+
+	next := &models.Snapshot{
+		Host:        diff.Host,
+		Time:        diff.Time,
+		Ownership:   diff.Ownership,
+		Errors:      diff.Errors,
+		Duration:    diff.Duration,
+		Sockstat:    diff.Sockstat,
+		Counters:    diff.Counters,
+		Connections: make([]models.ConnectionItem, 0, len(base.Connections)+len(diff.Added)),
+	}
+
+	for _, conn := range base.Connections {
+		key := conn.Key()
+		if removed[key] {
+			continue
+		}
+		if changed, ok := updated[key]; ok {
+			conn = changed
+		}
+		next.Connections = append(next.Connections, conn)
+	}
+	next.Connections = append(next.Connections, diff.Added...)
+
+	return next
+}
@@ -0,0 +1,37 @@
+package connections
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// ListenSet holds the local ports something listens on, which is how a
+// connection's direction is guessed: sockets sharing a listener's port were
+// accepted, the rest were dialled out.
+type ListenSet map[string]bool
+
+func listenKey(conn models.ConnectionItem) string {
+	return fmt.Sprintf("%s|%s|%d", conn.Host, strings.TrimSuffix(conn.Proto, "6"), conn.LocalPort)
+}
+
+func Listeners(connections []models.ConnectionItem) ListenSet {
+	set := make(ListenSet)
+	for _, conn := range connections {
+		if conn.IsListening() {
+			set[listenKey(conn)] = true
+		}
+	}
+	return set
+}
+
+func (l ListenSet) IsOutbound(conn models.ConnectionItem) bool {
+	return !conn.IsListening() && conn.RemotePort != 0 && !l[listenKey(conn)]
+}
+
+// IsInbound reports whether conn was accepted on one of the listening
+// ports.
+func (l ListenSet) IsInbound(conn models.ConnectionItem) bool {
+	return !conn.IsListening() && conn.RemotePort != 0 && l[listenKey(conn)]
+}
@@ -0,0 +1,197 @@
+package connections
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+const deletedSuffix = " (deleted)"
+
+// unsafeDirs hold files any user can drop, the usual home of malware that
+// could not write anywhere better.
+var unsafeDirs = []string{"/tmp", "/var/tmp", "/dev/shm", "/run/shm"}
+
+type exeInfo struct {
+	path      string
+	deleted   bool
+	replaced  bool
+	unsafeDir bool
+	sha256    string
+}
+
+func (e exeInfo) apply(conn *models.ConnectionItem) {
+	conn.Exe = e.path
+	conn.ExeDeleted = e.deleted
+	conn.ExeReplaced = e.replaced
+	conn.ExeUnsafeDir = e.unsafeDir
+	conn.ExeSHA256 = e.sha256
+}
+
+// inspectExe looks at the binary a process is running. /proc/<pid>/exe
+// opens the mapped file even after it was deleted, and /proc/<pid>/root
+// resolves the path inside the process's own mount namespace.
+func (c *Collector) inspectExe(pid string) exeInfo {
+	link := filepath.Join(c.ProcRoot, pid, "exe")
+	target, err := os.Readlink(link)
+	if err != nil {
+		return exeInfo{}
+	}
+
+	info := exeInfo{
+		path:    strings.TrimSuffix(target, deletedSuffix),
+		deleted: strings.HasSuffix(target, deletedSuffix),
+	}
+
+	running, err := os.Stat(link)
+	if err != nil {
+		return info
+	}
+
+	// A binary swapped out by rename shows as deleted with a new file at
+	// the same path; both cases count as replaced.
+	onDisk := filepath.Join(c.ProcRoot, pid, "root", info.path)
+	if current, err := os.Stat(onDisk); err == nil && !os.SameFile(running, current) {
+		info.replaced = true
+	}
+	info.unsafeDir = isUnsafeDir(info.path, filepath.Dir(onDisk))
+	if c.hashes != nil {
+		info.sha256 = c.hashes.lookup(info.path, link, running)
+	}
+	return info
+}
+
+func isUnsafeDir(path, dirOnDisk string) bool {
+	for _, dir := range unsafeDirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	if st, err := os.Stat(dirOnDisk); err == nil {
+		return st.Mode().Perm()&0o002 != 0
+	}
+	return false
+}
+
+const (
+	hashQueueSize = 64
+	// hashIdleTTL is how long a hash is kept after the last process
+	// running that binary was seen.
+	hashIdleTTL = 10 * time.Minute
+)
+
+type hashEntry struct {
+	sum      string
+	pending  bool
+	lastSeen time.Time
+}
+
+// exeHasher computes executable hashes on a background goroutine so a
+// large binary never holds up a collection; a hash first appears on the
+// snapshot after it finishes. Entries are keyed by path, device, inode,
+// size and mtime, so each binary is read once no matter how many
+// processes run it and a file rewritten in place is hashed again.
+type exeHasher struct {
+	jobs  chan hashJob
+	start sync.Once
+
+	mu        sync.Mutex
+	entries   map[string]hashEntry
+	lastPrune time.Time
+}
+
+type hashJob struct {
+	key  string
+	link string
+}
+
+func newExeHasher() *exeHasher {
+	return &exeHasher{
+		jobs:    make(chan hashJob, hashQueueSize),
+		entries: make(map[string]hashEntry),
+	}
+}
+
+// lookup returns the cached hash for the running binary, queueing it for
+// hashing if it is unknown.
+func (h *exeHasher) lookup(path, link string, running os.FileInfo) string {
+	st, ok := running.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	key := fmt.Sprintf("%s|%d:%d:%d:%d", path, st.Dev, st.Ino, running.Size(), running.ModTime().UnixNano())
+	now := time.Now()
+	h.start.Do(func() { go h.worker() })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if now.Sub(h.lastPrune) >= time.Minute {
+		h.prune(now)
+	}
+
+	entry, ok := h.entries[key]
+	entry.lastSeen = now
+	if !ok {
+		select {
+		case h.jobs <- hashJob{key: key, link: link}:
+			entry.pending = true
+		default:
+			// Queue full; the next collection asks again.
+			return ""
+		}
+	}
+	h.entries[key] = entry
+	return entry.sum
+}
+
+// prune forgets binaries no process has run for hashIdleTTL. Called with
+// h.mu held.
+func (h *exeHasher) prune(now time.Time) {
+	for key, entry := range h.entries {
+		if !entry.pending && now.Sub(entry.lastSeen) > hashIdleTTL {
+			delete(h.entries, key)
+		}
+	}
+	h.lastPrune = now
+}
+
+func (h *exeHasher) worker() {
+	for job := range h.jobs {
+		sum, err := hashFile(job.link)
+
+		h.mu.Lock()
+		if err != nil {
+			// The process may have exited; try again when it is next seen.
+			delete(h.entries, job.key)
+		} else {
+			entry := h.entries[job.key]
+			entry.sum, entry.pending = sum, false
+			h.entries[job.key] = entry
+		}
+		h.mu.Unlock()
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,193 @@
+//go:build linux
+
+package connections
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inetDiagInfo      = 2
+	sizeofSockID      = 48
+	sizeofInetDiagReq = 8 + sizeofSockID
+	sizeofInetDiagMsg = 4 + sizeofSockID + 20
+	allStates         = 0xffffffff
+)
+
+type DiagSocket struct {
+	Proto      string
+	State      uint8
+	LocalIP    string
+	LocalPort  int
+	RemoteIP   string
+	RemotePort int
+	RxQueue    uint32
+	TxQueue    uint32
+	UID        uint32
+	Inode      string
+	TCP        *models.TCPMetrics
+}
+
+func QueryInetDiag(protocol uint8) ([]DiagSocket, error) {
+	var sockets []DiagSocket
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		found, err := queryInetDiagFamily(family, protocol)
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, found...)
+	}
+	return sockets, nil
+}
+
+func queryInetDiagFamily(family, protocol uint8) ([]DiagSocket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_SOCK_DIAG)
+	if err != nil {
+		return nil, fmt.Errorf("inet_diag socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	tv := unix.NsecToTimeval(int64(2 * time.Second))
+	unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("inet_diag bind: %v", err)
+	}
+
+	req := make([]byte, unix.SizeofNlMsghdr+sizeofInetDiagReq)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], unix.SOCK_DIAG_BY_FAMILY)
+	binary.NativeEndian.PutUint16(req[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+
+	body := req[unix.SizeofNlMsghdr:]
+	body[0] = family
+	body[1] = protocol
+	if protocol == unix.IPPROTO_TCP {
+		body[2] = 1 << (inetDiagInfo - 1)
+	}
+	binary.NativeEndian.PutUint32(body[4:8], allStates)
+
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("inet_diag send: %v", err)
+	}
+
+	var sockets []DiagSocket
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("inet_diag recv: %v", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("inet_diag parse: %v", err)
+		}
+
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return sockets, nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(msg.Data[0:4])); errno != 0 {
+						return nil, fmt.Errorf("inet_diag: %v", unix.Errno(-errno))
+					}
+				}
+				return sockets, nil
+			}
+
+			if socket, ok := parseInetDiagMsg(msg.Data, family, protocol); ok {
+				sockets = append(sockets, socket)
+			}
+		}
+	}
+}
+
+func parseInetDiagMsg(data []byte, family, protocol uint8) (DiagSocket, bool) {
+	if len(data) < sizeofInetDiagMsg {
+		return DiagSocket{}, false
+	}
+
+	proto := "TCP"
+	if protocol == unix.IPPROTO_UDP {
+		proto = "UDP"
+	}
+	if family == unix.AF_INET6 {
+		proto += "6"
+	}
+
+	id := data[4 : 4+sizeofSockID]
+	socket := DiagSocket{
+		Proto:      proto,
+		State:      data[1],
+		LocalPort:  int(binary.BigEndian.Uint16(id[0:2])),
+		RemotePort: int(binary.BigEndian.Uint16(id[2:4])),
+		LocalIP:    diagAddr(family, id[4:20]),
+		RemoteIP:   diagAddr(family, id[20:36]),
+	}
+
+	tail := data[4+sizeofSockID:]
+	socket.RxQueue = binary.NativeEndian.Uint32(tail[4:8])
+	socket.TxQueue = binary.NativeEndian.Uint32(tail[8:12])
+	socket.UID = binary.NativeEndian.Uint32(tail[12:16])
+	socket.Inode = strconv.FormatUint(uint64(binary.NativeEndian.Uint32(tail[16:20])), 10)
+
+	attrs := data[sizeofInetDiagMsg:]
+	for len(attrs) >= unix.SizeofRtAttr {
+		attrLen := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		attrType := binary.NativeEndian.Uint16(attrs[2:4])
+		if attrLen < unix.SizeofRtAttr || attrLen > len(attrs) {
+			break
+		}
+
+		if attrType == inetDiagInfo {
+			socket.TCP = decodeTCPInfo(attrs[unix.SizeofRtAttr:attrLen])
+		}
+
+		aligned := (attrLen + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+
+	return socket, true
+}
+
+func diagAddr(family uint8, raw []byte) string {
+	if family == unix.AF_INET {
+		return net.IP(raw[:4]).String()
+	}
+	return net.IP(raw[:16]).String()
+}
+
+func decodeTCPInfo(raw []byte) *models.TCPMetrics {
+	// Older kernels send a shorter tcp_info; missing trailing fields stay zero.
+	var info unix.TCPInfo
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&info)), unix.SizeofTCPInfo), raw)
+
+	return &models.TCPMetrics{
+		RTT:           time.Duration(info.Rtt) * time.Microsecond,
+		RTTVar:        time.Duration(info.Rttvar) * time.Microsecond,
+		Cwnd:          info.Snd_cwnd,
+		Retransmits:   info.Total_retrans,
+		SegsOut:       info.Segs_out,
+		BytesSent:     info.Bytes_sent,
+		BytesAcked:    info.Bytes_acked,
+		BytesReceived: info.Bytes_received,
+		DeliveryRate:  info.Delivery_rate,
+		LastSend:      time.Duration(info.Last_data_sent) * time.Millisecond,
+		LastReceive:   time.Duration(info.Last_data_recv) * time.Millisecond,
+	}
+}
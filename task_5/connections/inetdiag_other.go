@@ -0,0 +1,31 @@
+//go:build !linux
+
+package connections
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type DiagSocket struct {
+	Proto      string
+	State      uint8
+	LocalIP    string
+	LocalPort  int
+	RemoteIP   string
+	RemotePort int
+	RxQueue    uint32
+	TxQueue    uint32
+	UID        uint32
+	Inode      string
+	TCP        *models.TCPMetrics
+}
+
+func QueryInetDiag(protocol uint8) ([]DiagSocket, error) {
+	return nil, fmt.Errorf("inet_diag is only available on Linux")
+}
+
+func DestroySocket(conn models.ConnectionItem) error {
+	return fmt.Errorf("destroying sockets is only available on Linux")
+}
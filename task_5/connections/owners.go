@@ -0,0 +1,45 @@
+package connections
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// SocketOwners rescans the process table for every PID holding the socket
+// inode. Sockets inherited across fork() have several owners, while the
+// snapshot only keeps one of them.
+func (c *Collector) SocketOwners(inode string) []string {
+	if inode == "" || inode == "0" {
+		return nil
+	}
+	target := "socket:[" + inode + "]"
+
+	procDirs, err := filepath.Glob(filepath.Join(c.ProcRoot, "[0-9]*"))
+	if err != nil {
+		return nil
+	}
+
+	var owners []string
+	for _, procDir := range procDirs {
+		fdDir := filepath.Join(procDir, "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && link == target {
+				owners = append(owners, filepath.Base(procDir))
+				break
+			}
+		}
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		a, _ := strconv.Atoi(owners[i])
+		b, _ := strconv.Atoi(owners[j])
+		return a < b
+	})
+	return owners
+}
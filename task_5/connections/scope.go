@@ -0,0 +1,36 @@
+package connections
+
+import (
+	"net/netip"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+func AddressScope(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "unknown"
+	}
+	addr = addr.Unmap()
+
+	switch {
+	case addr.IsUnspecified():
+		return "any"
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsMulticast():
+		return "multicast"
+	case addr.IsLinkLocalUnicast():
+		return "link-local"
+	case addr.IsPrivate():
+		return "private"
+	}
+	return "public"
+}
+
+func ConnectionScope(c models.ConnectionItem) string {
+	if c.IsListening() || c.RemoteIP == "" || AddressScope(c.RemoteIP) == "any" {
+		return AddressScope(c.LocalIP)
+	}
+	return AddressScope(c.RemoteIP)
+}
@@ -0,0 +1,106 @@
+package conntrack
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// Read returns the conntrack table, preferring ctnetlink and falling back to
+// /proc/net/nf_conntrack. Only the host's own /proc can be dumped over
+// netlink.
+func Read(procRoot string) ([]models.ConntrackEntry, error) {
+	if procRoot != "/proc" {
+		return ReadProc(procRoot)
+	}
+
+	entries, err := Dump()
+	if err == nil {
+		return entries, nil
+	}
+	entries, procErr := ReadProc(procRoot)
+	if procErr != nil {
+		return nil, fmt.Errorf("conntrack: %v; %v", err, procErr)
+	}
+	return entries, nil
+}
+
+// Match links conntrack entries to the local sockets they track. A socket
+// can sit on either end of either tuple: outbound flows match the original
+// tuple, inbound ones its mirror, and connections redirected or DNATed to
+// this host only show up in the reply tuple.
+func Match(conns []models.ConnectionItem, entries []models.ConntrackEntry) {
+	index := make(map[string]int)
+	for i, conn := range conns {
+		if conn.RemotePort == 0 {
+			continue
+		}
+		proto := strings.ToLower(strings.TrimSuffix(conn.Proto, "6"))
+		index[tupleKey(proto, conn.LocalIP, conn.LocalPort, conn.RemoteIP, conn.RemotePort)] = i
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		o, r := e.Original, e.Reply
+		for _, key := range []string{
+			tupleKey(e.Proto, o.Src, o.SPort, o.Dst, o.DPort),
+			tupleKey(e.Proto, o.Dst, o.DPort, o.Src, o.SPort),
+			tupleKey(e.Proto, r.Src, r.SPort, r.Dst, r.DPort),
+			tupleKey(e.Proto, r.Dst, r.DPort, r.Src, r.SPort),
+		} {
+			j, ok := index[key]
+			if !ok {
+				continue
+			}
+			e.PID, e.Process = conns[j].PID, conns[j].Process
+			entry := *e
+			conns[j].Conntrack = &entry
+			break
+		}
+	}
+}
+
+func tupleKey(proto, localIP string, localPort int, remoteIP string, remotePort int) string {
+	return proto + "|" + net.JoinHostPort(localIP, strconv.Itoa(localPort)) + "|" + net.JoinHostPort(remoteIP, strconv.Itoa(remotePort))
+}
+
+// Collector attaches the conntrack table to each snapshot of the local
+// host. Failing to read it is reported in the snapshot, not as an error.
+type Collector struct {
+	inner    models.Collector
+	procRoot string
+}
+
+func NewCollector(inner models.Collector, procRoot string) *Collector {
+	return &Collector{inner: inner, procRoot: procRoot}
+}
+
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	snapshot, err := c.inner.Collect()
+	if err != nil {
+		return snapshot, err
+	}
+
+	entries, err := Read(c.procRoot)
+	if err != nil {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+		return snapshot, nil
+	}
+	Match(snapshot.Connections, entries)
+	snapshot.Conntrack = entries
+	return snapshot, nil
+}
+
+func (c *Collector) Hostname() string {
+	return c.inner.Hostname()
+}
+
+func (c *Collector) Health() []models.HostHealth {
+	if reporter, ok := c.inner.(models.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
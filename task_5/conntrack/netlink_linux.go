@@ -0,0 +1,251 @@
+//go:build linux
+
+package conntrack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ipctnlMsgCtGet = 1
+	nlaTypeMask    = 0x3fff
+
+	ctaTupleOrig  = 1
+	ctaTupleReply = 2
+	ctaStatus     = 3
+	ctaProtoinfo  = 4
+	ctaTimeout    = 7
+	ctaMark       = 8
+	ctaZone       = 18
+
+	ctaTupleIP    = 1
+	ctaTupleProto = 2
+
+	ctaIPv4Src = 1
+	ctaIPv4Dst = 2
+	ctaIPv6Src = 3
+	ctaIPv6Dst = 4
+
+	ctaProtoNum     = 1
+	ctaProtoSrcPort = 2
+	ctaProtoDstPort = 3
+
+	ctaProtoinfoTCP      = 1
+	ctaProtoinfoTCPState = 1
+
+	ipsSeenReply = 1 << 1
+	ipsAssured   = 1 << 2
+)
+
+var tcpStates = []string{
+	"NONE", "SYN_SENT", "SYN_RECV", "ESTABLISHED", "FIN_WAIT",
+	"CLOSE_WAIT", "LAST_ACK", "TIME_WAIT", "CLOSE", "SYN_SENT2",
+}
+
+// Dump reads the conntrack table over ctnetlink, which works on kernels
+// built without the /proc/net/nf_conntrack file. It needs CAP_NET_ADMIN.
+func Dump() ([]models.ConntrackEntry, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("ctnetlink socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	tv := unix.NsecToTimeval(int64(2 * time.Second))
+	unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("ctnetlink bind: %v", err)
+	}
+
+	// nlmsghdr followed by nfgenmsg; AF_UNSPEC dumps every family.
+	req := make([]byte, unix.SizeofNlMsghdr+4)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], unix.NFNL_SUBSYS_CTNETLINK<<8|ipctnlMsgCtGet)
+	binary.NativeEndian.PutUint16(req[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	req[unix.SizeofNlMsghdr] = unix.AF_UNSPEC
+	req[unix.SizeofNlMsghdr+1] = unix.NFNETLINK_V0
+
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("ctnetlink send: %v", err)
+	}
+
+	var entries []models.ConntrackEntry
+	buf := make([]byte, 256*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("ctnetlink recv: %v", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("ctnetlink parse: %v", err)
+		}
+
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return entries, nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(msg.Data[0:4])); errno != 0 {
+						return nil, fmt.Errorf("ctnetlink: %v", unix.Errno(-errno))
+					}
+				}
+				return entries, nil
+			}
+
+			if len(msg.Data) < 4 {
+				continue
+			}
+			if entry, ok := parseEntry(msg.Data[0], msg.Data[4:]); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+}
+
+func parseEntry(family uint8, data []byte) (models.ConntrackEntry, bool) {
+	entry := models.ConntrackEntry{Family: "ipv4"}
+	if family == unix.AF_INET6 {
+		entry.Family = "ipv6"
+	}
+
+	var status uint32
+	var proto uint8
+	for typ, value := range attrs(data) {
+		switch typ {
+		case ctaTupleOrig:
+			entry.Original, proto = parseTuple(value)
+		case ctaTupleReply:
+			entry.Reply, _ = parseTuple(value)
+		case ctaStatus:
+			status = be32(value)
+		case ctaTimeout:
+			entry.Timeout = time.Duration(be32(value)) * time.Second
+		case ctaMark:
+			entry.Mark = be32(value)
+		case ctaZone:
+			if len(value) >= 2 {
+				entry.Zone = binary.BigEndian.Uint16(value)
+			}
+		case ctaProtoinfo:
+			entry.State = parseTCPState(value)
+		}
+	}
+
+	entry.Proto = protoName(proto)
+	if status&ipsSeenReply == 0 {
+		entry.Flags = append(entry.Flags, "UNREPLIED")
+	}
+	if status&ipsAssured != 0 {
+		entry.Flags = append(entry.Flags, "ASSURED")
+	}
+	return entry, entry.Original.Src != ""
+}
+
+func parseTuple(data []byte) (models.ConntrackTuple, uint8) {
+	var tuple models.ConntrackTuple
+	var proto uint8
+
+	for typ, value := range attrs(data) {
+		switch typ {
+		case ctaTupleIP:
+			for ipType, addr := range attrs(value) {
+				switch ipType {
+				case ctaIPv4Src, ctaIPv6Src:
+					tuple.Src = net.IP(addr).String()
+				case ctaIPv4Dst, ctaIPv6Dst:
+					tuple.Dst = net.IP(addr).String()
+				}
+			}
+		case ctaTupleProto:
+			for protoType, v := range attrs(value) {
+				switch protoType {
+				case ctaProtoNum:
+					if len(v) >= 1 {
+						proto = v[0]
+					}
+				case ctaProtoSrcPort:
+					if len(v) >= 2 {
+						tuple.SPort = int(binary.BigEndian.Uint16(v))
+					}
+				case ctaProtoDstPort:
+					if len(v) >= 2 {
+						tuple.DPort = int(binary.BigEndian.Uint16(v))
+					}
+				}
+			}
+		}
+	}
+	return tuple, proto
+}
+
+func parseTCPState(data []byte) string {
+	for typ, value := range attrs(data) {
+		if typ != ctaProtoinfoTCP {
+			continue
+		}
+		for field, v := range attrs(value) {
+			if field == ctaProtoinfoTCPState && len(v) >= 1 && int(v[0]) < len(tcpStates) {
+				return tcpStates[v[0]]
+			}
+		}
+	}
+	return ""
+}
+
+// attrs indexes a run of netlink attributes by type, with the nested flag
+// stripped. ctnetlink never repeats an attribute at one level.
+func attrs(data []byte) map[uint16][]byte {
+	found := make(map[uint16][]byte)
+	for len(data) >= 4 {
+		length := int(binary.NativeEndian.Uint16(data[0:2]))
+		typ := binary.NativeEndian.Uint16(data[2:4]) & nlaTypeMask
+		if length < 4 || length > len(data) {
+			break
+		}
+		found[typ] = data[4:length]
+
+		aligned := (length + 3) &^ 3
+		if aligned > len(data) {
+			break
+		}
+		data = data[aligned:]
+	}
+	return found
+}
+
+func be32(b []byte) uint32 {
+	if len(b) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func protoName(proto uint8) string {
+	switch proto {
+	case unix.IPPROTO_TCP:
+		return "tcp"
+	case unix.IPPROTO_UDP:
+		return "udp"
+	case unix.IPPROTO_ICMP:
+		return "icmp"
+	case unix.IPPROTO_ICMPV6:
+		return "icmpv6"
+	case unix.IPPROTO_SCTP:
+		return "sctp"
+	case unix.IPPROTO_UDPLITE:
+		return "udplite"
+	}
+	return fmt.Sprintf("proto-%d", proto)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package conntrack
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+func Dump() ([]models.ConntrackEntry, error) {
+	return nil, fmt.Errorf("ctnetlink is only available on Linux")
+}
@@ -0,0 +1,113 @@
+package conntrack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// ReadProc parses the legacy /proc/net/nf_conntrack table, which kernels
+// only provide with CONFIG_NF_CONNTRACK_PROCFS.
+func ReadProc(procRoot string) ([]models.ConntrackEntry, error) {
+	file, err := os.Open(filepath.Join(procRoot, "net", "nf_conntrack"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseProc(file)
+}
+
+func parseProc(r io.Reader) ([]models.ConntrackEntry, error) {
+	var entries []models.ConntrackEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 64*1024)
+	for scanner.Scan() {
+		if entry, ok := parseProcLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("nf_conntrack: %v", err)
+	}
+	return entries, nil
+}
+
+// parseProcLine reads a line such as
+//
+//	ipv4 2 tcp 6 431999 ESTABLISHED src=10.0.0.5 dst=1.2.3.4 sport=40000 dport=443 src=1.2.3.4 dst=192.0.2.1 sport=443 dport=40000 [ASSURED] mark=0 zone=0 use=2
+//
+// The first src/dst/sport/dport group is the original tuple, the second
+// the reply tuple.
+func parseProcLine(line string) (models.ConntrackEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return models.ConntrackEntry{}, false
+	}
+
+	entry := models.ConntrackEntry{Family: fields[0], Proto: fields[2]}
+	if secs, err := strconv.Atoi(fields[4]); err == nil {
+		entry.Timeout = time.Duration(secs) * time.Second
+	}
+
+	rest := fields[5:]
+	if !strings.Contains(rest[0], "=") && !strings.HasPrefix(rest[0], "[") {
+		entry.State = rest[0]
+		rest = rest[1:]
+	}
+
+	tuple := &entry.Original
+	seen := make(map[string]bool)
+	for _, field := range rest {
+		if strings.HasPrefix(field, "[") {
+			entry.Flags = append(entry.Flags, strings.Trim(field, "[]"))
+			continue
+		}
+
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		if seen[key] && tuple == &entry.Original {
+			tuple = &entry.Reply
+			seen = make(map[string]bool)
+		}
+		seen[key] = true
+
+		switch key {
+		case "src":
+			tuple.Src = normalizeIP(value)
+		case "dst":
+			tuple.Dst = normalizeIP(value)
+		case "sport":
+			tuple.SPort, _ = strconv.Atoi(value)
+		case "dport":
+			tuple.DPort, _ = strconv.Atoi(value)
+		case "mark":
+			mark, _ := strconv.ParseUint(value, 0, 32)
+			entry.Mark = uint32(mark)
+		case "zone":
+			zone, _ := strconv.ParseUint(value, 10, 16)
+			entry.Zone = uint16(zone)
+		}
+	}
+
+	return entry, entry.Original.Src != ""
+}
+
+// normalizeIP compresses the zero-padded IPv6 form the kernel prints so
+// addresses compare equal to the socket tables'.
+func normalizeIP(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	return addr
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// runCrossCheck looks for sockets hidden from one of the kernel's views.
+// Only findings seen on every pass are reported; it exits 1 when there are
+// any and 2 when the check cannot run.
+func runCrossCheck(args []string) {
+	fs := flag.NewFlagSet("crosscheck", flag.ExitOnError)
+	passes := fs.Int("passes", 3, "number of passes a finding must appear in")
+	interval := fs.Duration("interval", time.Second, "pause between passes")
+	fs.Parse(args)
+
+	collector := connections.Default()
+
+	var findings []models.Alert
+	seen := make(map[string]int)
+	for i := 0; i < max(1, *passes); i++ {
+		if i > 0 {
+			time.Sleep(*interval)
+		}
+		pass, err := collector.CrossCheck()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(2)
+		}
+		findings = pass
+		for _, finding := range pass {
+			seen[finding.Key]++
+		}
+	}
+
+	var confirmed []models.Alert
+	for _, finding := range findings {
+		if seen[finding.Key] == max(1, *passes) {
+			confirmed = append(confirmed, finding)
+		}
+	}
+	sort.Slice(confirmed, func(i, j int) bool {
+		return confirmed[i].Key < confirmed[j].Key
+	})
+
+	for _, finding := range confirmed {
+		fmt.Printf("%-8s %s\n", strings.ToUpper(finding.Severity), finding.Message)
+	}
+	if len(confirmed) > 0 {
+		fmt.Printf("%d findings\n", len(confirmed))
+		os.Exit(1)
+	}
+	fmt.Println("All socket sources agree")
+}
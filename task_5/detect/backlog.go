@@ -0,0 +1,71 @@
+package detect
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type BacklogOptions struct {
+	Threshold float64 `json:"threshold"`
+	Ticks     int     `json:"ticks"`
+}
+
+func DefaultBacklogOptions() BacklogOptions {
+	return BacklogOptions{Threshold: 0.9, Ticks: 3}
+}
+
+// BacklogMonitor raises an alert when a listener's accept queue stays at or
+// above Threshold of its backlog for Ticks consecutive snapshots, which
+// usually means the owning service has stopped calling accept().
+type BacklogMonitor struct {
+	opts    BacklogOptions
+	streaks map[string]int
+}
+
+func NewBacklogMonitor(opts BacklogOptions) *BacklogMonitor {
+	defaults := DefaultBacklogOptions()
+	if opts.Threshold <= 0 {
+		opts.Threshold = defaults.Threshold
+	}
+	if opts.Ticks <= 0 {
+		opts.Ticks = defaults.Ticks
+	}
+	return &BacklogMonitor{opts: opts, streaks: make(map[string]int)}
+}
+
+func (b *BacklogMonitor) Observe(snapshot *models.Snapshot) []models.Alert {
+	var alerts []models.Alert
+	streaks := make(map[string]int)
+
+	for _, conn := range snapshot.Connections {
+		if !conn.HasBacklog() || conn.BacklogFill() < b.opts.Threshold {
+			continue
+		}
+
+		key := conn.Host + "|" + conn.Proto + "|" + conn.Local
+		streaks[key] = b.streaks[key] + 1
+		if streaks[key] < b.opts.Ticks {
+			continue
+		}
+
+		severity := models.SeverityWarning
+		if conn.RxQueue >= conn.TxQueue {
+			severity = models.SeverityCritical
+		}
+
+		listener := conn
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: severity,
+			Source:   "backlog",
+			Key:      "backlog|" + key,
+			Message: fmt.Sprintf("%s %s (%s) accept queue %d/%d for %d ticks",
+				conn.Proto, conn.Local, conn.Process, conn.RxQueue, conn.TxQueue, streaks[key]),
+			Connection: &listener,
+		})
+	}
+
+	b.streaks = streaks
+	return alerts
+}
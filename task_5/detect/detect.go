@@ -0,0 +1,90 @@
+package detect
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type Detector interface {
+	Observe(snapshot *models.Snapshot) []models.Alert
+}
+
+type Collector struct {
+	inner     models.Collector
+	detectors []Detector
+
+	mu     sync.Mutex
+	lastAt time.Time
+	last   []models.Alert
+}
+
+func NewCollector(inner models.Collector, detectors ...Detector) *Collector {
+	return &Collector{inner: inner, detectors: detectors}
+}
+
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	snapshot, err := c.inner.Collect()
+	if err != nil {
+		return snapshot, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A remote agent may hand back the same snapshot twice; detectors count
+	// ticks, so only feed them new data.
+	if snapshot.Time.After(c.lastAt) {
+		c.lastAt = snapshot.Time
+		c.last = nil
+		for _, detector := range c.detectors {
+			c.last = append(c.last, detector.Observe(snapshot)...)
+		}
+	}
+
+	snapshot.Alerts = append(snapshot.Alerts, c.last...)
+	return snapshot, nil
+}
+
+// AlertEvents turns alerts that were not raised on the previous snapshot
+// into events, so a condition is logged once rather than on every tick.
+func AlertEvents(prev, next []models.Alert) []models.Event {
+	seen := make(map[string]bool, len(prev))
+	for _, alert := range prev {
+		seen[alert.Key] = true
+	}
+
+	var events []models.Event
+	for _, alert := range next {
+		if seen[alert.Key] {
+			continue
+		}
+		seen[alert.Key] = true
+
+		event := models.Event{
+			Time:     alert.Time,
+			Kind:     models.EventAlert,
+			Message:  alert.Message,
+			Severity: alert.Severity,
+			Source:   alert.Source,
+			Evidence: alert.Evidence,
+		}
+		if alert.Connection != nil {
+			event.Connection = *alert.Connection
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func (c *Collector) Hostname() string {
+	return c.inner.Hostname()
+}
+
+func (c *Collector) Health() []models.HostHealth {
+	if reporter, ok := c.inner.(models.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package detect
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/services"
+)
+
+var (
+	stdioNames = []string{"stdin", "stdout", "stderr"}
+	// interpreter matches the executable name of a scripting interpreter,
+	// optionally versioned (python3.11, php8.2), but not related binaries
+	// such as php-fpm or python3-config.
+	interpreter = regexp.MustCompile(`^(python|perl|php|ruby|lua|luajit|tclsh)[0-9.]*$`)
+	// workerPools are long-running application servers built on an
+	// interpreter whose outbound connections are routine.
+	workerPools = []string{"gunicorn", "uwsgi", "uvicorn", "hypercorn", "daphne", "celery", "php-fpm", "supervisord"}
+)
+
+// ReverseShellDetector looks for the shapes reverse shells and tunnels
+// leave in the socket table: a process whose stdio is a network socket,
+// a scripting interpreter dialling out, and connections that stay up on
+// ports favoured by attack tooling.
+type ReverseShellDetector struct {
+	// MinAge is how long a connection to an abused port must last before
+	// it is reported; short ones are usually scanners or typos.
+	MinAge    time.Duration
+	firstSeen map[string]time.Time
+}
+
+func NewReverseShellDetector() *ReverseShellDetector {
+	return &ReverseShellDetector{MinAge: time.Minute, firstSeen: make(map[string]time.Time)}
+}
+
+func (d *ReverseShellDetector) Observe(snapshot *models.Snapshot) []models.Alert {
+	listening := connections.Listeners(snapshot.Connections)
+	seen := make(map[string]time.Time)
+
+	var alerts []models.Alert
+	for _, conn := range snapshot.Connections {
+		if conn.IsListening() || conn.RemotePort == 0 {
+			continue
+		}
+		key := conn.Key()
+		first, ok := d.firstSeen[key]
+		if !ok {
+			first = snapshot.Time
+		}
+		seen[key] = first
+
+		flagged := conn
+		alert := func(severity, kind, message string, evidence []string) {
+			alerts = append(alerts, models.Alert{
+				Time:       snapshot.Time,
+				Severity:   severity,
+				Source:     "revshell",
+				Key:        "revshell|" + kind + "|" + key,
+				Message:    message,
+				Evidence:   evidence,
+				Connection: &flagged,
+			})
+		}
+
+		if len(conn.Stdio) > 0 {
+			var evidence []string
+			for _, ref := range conn.Stdio {
+				// Stdio can come from a remote agent, so the FD is not
+				// trusted to be 0..2.
+				name := fmt.Sprintf("fd %d", ref.FD)
+				if ref.FD >= 0 && ref.FD < len(stdioNames) {
+					name = stdioNames[ref.FD]
+				}
+				evidence = append(evidence, fmt.Sprintf("/proc/%s/fd/%d (%s of %s) -> socket:[%s]",
+					ref.PID, ref.FD, name, ref.Process, conn.Inode))
+			}
+			evidence = append(evidence, fmt.Sprintf("socket is %s %s → %s %s", conn.Proto, conn.Local, conn.Remote, conn.State))
+			alert(models.SeverityCritical, "stdio",
+				fmt.Sprintf("%s (PID %s) has its stdio on a network socket to %s", conn.Stdio[0].Process, conn.Stdio[0].PID, conn.Remote),
+				evidence)
+		}
+
+		if name, ok := interpreterName(conn); ok && listening.IsOutbound(conn) && connections.AddressScope(conn.RemoteIP) != "loopback" {
+			alert(models.SeverityWarning, "interpreter",
+				fmt.Sprintf("interpreter %s (PID %s) has an outbound connection to %s", conn.Process, conn.PID, conn.Remote),
+				[]string{
+					fmt.Sprintf("executable %q is a scripting interpreter", name),
+					fmt.Sprintf("local port %d is not a listening port, so the connection was dialled out", conn.LocalPort),
+					fmt.Sprintf("remote %s is %s", conn.RemoteIP, connections.AddressScope(conn.RemoteIP)),
+				})
+		}
+
+		if age := snapshot.Time.Sub(first); age >= d.MinAge {
+			if port, service, ok := services.Default().Abused(conn.Proto, conn.RemotePort, conn.LocalPort); ok {
+				alert(models.SeverityWarning, "port",
+					fmt.Sprintf("%s (PID %s) has kept a connection on port %d/%s open for %s", conn.Process, conn.PID, port, service, age.Round(time.Second)),
+					[]string{
+						fmt.Sprintf("port %d is a default of %s tooling", port, service),
+						fmt.Sprintf("connection %s → %s first seen %s", conn.Local, conn.Remote, first.Format("15:04:05")),
+					})
+			}
+		}
+	}
+
+	d.firstSeen = seen
+	return alerts
+}
+
+// interpreterName returns the interpreter conn's process runs, judged by
+// the executable when it is known and the process name otherwise. A
+// script started through its shebang keeps the interpreter as executable
+// but takes the script's name, which is how worker pools are told apart.
+func interpreterName(conn models.ConnectionItem) (string, bool) {
+	name := conn.Process
+	if conn.Exe != "" {
+		name = filepath.Base(conn.Exe)
+	}
+	name = strings.ToLower(name)
+	if !interpreter.MatchString(name) {
+		return "", false
+	}
+
+	process := strings.ToLower(conn.Process)
+	for _, pool := range workerPools {
+		if strings.HasPrefix(process, pool) {
+			return "", false
+		}
+	}
+	return name, true
+}
@@ -0,0 +1,276 @@
+package detect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type ScanOptions struct {
+	// Window is how many snapshots the port-scan counts cover.
+	Window int `json:"window"`
+	// Ports is how many distinct local ports one source has to hit within
+	// the window to count as a scan. Only connections that reach a
+	// listening socket are visible per source, so this can never fire on
+	// a host with fewer listeners; ClosedPorts covers the rest.
+	Ports int `json:"ports"`
+	// ClosedPorts is how many probes of closed ports, counted as TCP
+	// resets sent plus UDP datagrams to unbound ports, the host may see
+	// within the window. The kernel only counts these host-wide, so the
+	// alert cannot name a source, and resets also include aborted
+	// connections.
+	ClosedPorts int `json:"closed_ports"`
+	// SynRecv is how many half-open connections one source may hold at
+	// once before it is treated as a SYN flood.
+	SynRecv int `json:"syn_recv"`
+	// TotalSynRecv catches floods spread over many (often spoofed) sources.
+	TotalSynRecv int `json:"total_syn_recv"`
+}
+
+func DefaultScanOptions() ScanOptions {
+	return ScanOptions{Window: 6, Ports: 10, ClosedPorts: 200, SynRecv: 20, TotalSynRecv: 200}
+}
+
+// closedPortCounters are the kernel counters that grow when a probe hits a
+// port nothing listens on.
+var closedPortCounters = map[string]bool{"Tcp.OutRsts": true, "Udp.NoPorts": true, "Udp6.NoPorts": true}
+
+// ScanDetector watches inbound connections across a sliding window of
+// snapshots for port scans and SYN floods.
+type ScanDetector struct {
+	opts         ScanOptions
+	prev         []models.ConnectionItem
+	prevCounters map[string]int64
+	window       []scanTick
+}
+
+// scanTick is what one snapshot added: local ports hit per source, and
+// closed-port probes per counter and host.
+type scanTick struct {
+	ports  map[string]map[int]bool
+	probes map[string]int64
+}
+
+func NewScanDetector(opts ScanOptions) *ScanDetector {
+	defaults := DefaultScanOptions()
+	if opts.Window <= 0 {
+		opts.Window = defaults.Window
+	}
+	if opts.Ports <= 0 {
+		opts.Ports = defaults.Ports
+	}
+	if opts.ClosedPorts <= 0 {
+		opts.ClosedPorts = defaults.ClosedPorts
+	}
+	if opts.SynRecv <= 0 {
+		opts.SynRecv = defaults.SynRecv
+	}
+	if opts.TotalSynRecv <= 0 {
+		opts.TotalSynRecv = defaults.TotalSynRecv
+	}
+	return &ScanDetector{opts: opts}
+}
+
+func (d *ScanDetector) Observe(snapshot *models.Snapshot) []models.Alert {
+	listening := connections.Listeners(snapshot.Connections)
+
+	var alerts []models.Alert
+	alerts = append(alerts, d.synFlood(snapshot)...)
+
+	// Sockets that appeared since the last snapshot, including ones that
+	// already reached TIME_WAIT, are what a scan leaves behind.
+	tick := make(map[string]map[int]bool)
+	if d.prev != nil {
+		opened, _ := connections.Diff(d.prev, snapshot.Connections)
+		for _, conn := range opened {
+			if !listening.IsInbound(conn) {
+				continue
+			}
+			source := conn.Host + "|" + conn.RemoteIP
+			if tick[source] == nil {
+				tick[source] = make(map[int]bool)
+			}
+			tick[source][conn.LocalPort] = true
+		}
+	}
+	d.prev = snapshot.Connections
+
+	d.window = append(d.window, scanTick{ports: tick, probes: d.probes(snapshot)})
+	if len(d.window) > d.opts.Window {
+		d.window = d.window[len(d.window)-d.opts.Window:]
+	}
+	alerts = append(alerts, d.closedPorts(snapshot)...)
+
+	ports := make(map[string]map[int]bool)
+	for _, t := range d.window {
+		for source, hit := range t.ports {
+			if ports[source] == nil {
+				ports[source] = make(map[int]bool)
+			}
+			for port := range hit {
+				ports[source][port] = true
+			}
+		}
+	}
+
+	for source, hit := range ports {
+		if len(hit) < d.opts.Ports {
+			continue
+		}
+		host, remote, _ := strings.Cut(source, "|")
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityWarning,
+			Source:   "scan",
+			Key:      "scan|ports|" + source,
+			Message: fmt.Sprintf("%s opened connections to %d local ports in the last %d snapshots",
+				remote, len(hit), len(d.window)),
+			Evidence: []string{
+				"ports: " + portList(hit, 20),
+				hostNote(host),
+			},
+		})
+	}
+
+	return alerts
+}
+
+// probes returns how much each closed-port counter grew since the last
+// snapshot, keyed by host and counter name.
+func (d *ScanDetector) probes(snapshot *models.Snapshot) map[string]int64 {
+	current := make(map[string]int64)
+	deltas := make(map[string]int64)
+	for _, c := range snapshot.Counters {
+		if !closedPortCounters[c.Name] {
+			continue
+		}
+		key := c.Host + "|" + c.Name
+		current[key] = c.Value
+		if prev, ok := d.prevCounters[key]; ok && c.Value >= prev {
+			deltas[key] = c.Value - prev
+		}
+	}
+	d.prevCounters = current
+	return deltas
+}
+
+func (d *ScanDetector) closedPorts(snapshot *models.Snapshot) []models.Alert {
+	perHost := make(map[string]map[string]int64)
+	for _, t := range d.window {
+		for key, delta := range t.probes {
+			host, name, _ := strings.Cut(key, "|")
+			if perHost[host] == nil {
+				perHost[host] = make(map[string]int64)
+			}
+			perHost[host][name] += delta
+		}
+	}
+
+	var alerts []models.Alert
+	for host, counts := range perHost {
+		var total int64
+		for _, count := range counts {
+			total += count
+		}
+		if total < int64(d.opts.ClosedPorts) {
+			continue
+		}
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityWarning,
+			Source:   "scan",
+			Key:      "scan|closed|" + host,
+			Message: fmt.Sprintf("%d probes of closed ports in the last %d snapshots",
+				total, len(d.window)),
+			Evidence: []string{
+				fmt.Sprintf("%d TCP resets sent, %d UDP datagrams to unbound ports (threshold %d)",
+					counts["Tcp.OutRsts"], counts["Udp.NoPorts"]+counts["Udp6.NoPorts"], d.opts.ClosedPorts),
+				"kernel counters are host-wide, so the source is unknown",
+				hostNote(host),
+			},
+		})
+	}
+	return alerts
+}
+
+func (d *ScanDetector) synFlood(snapshot *models.Snapshot) []models.Alert {
+	perSource := make(map[string]int)
+	perTarget := make(map[string]int)
+	total := 0
+	for _, conn := range snapshot.Connections {
+		if conn.State != "SYN_RECV" && conn.State != "NEW_SYN_RECV" {
+			continue
+		}
+		perSource[conn.Host+"|"+conn.RemoteIP]++
+		perTarget[conn.Host+"|"+conn.Local]++
+		total++
+	}
+
+	var alerts []models.Alert
+	for source, count := range perSource {
+		if count < d.opts.SynRecv {
+			continue
+		}
+		host, remote, _ := strings.Cut(source, "|")
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityCritical,
+			Source:   "scan",
+			Key:      "scan|synflood|" + source,
+			Message:  fmt.Sprintf("SYN flood from %s: %d half-open connections", remote, count),
+			Evidence: []string{
+				fmt.Sprintf("%d sockets in SYN_RECV from %s (threshold %d)", count, remote, d.opts.SynRecv),
+				hostNote(host),
+			},
+		})
+	}
+
+	if total >= d.opts.TotalSynRecv {
+		var targets []string
+		for target, count := range perTarget {
+			host, local, _ := strings.Cut(target, "|")
+			if host != "" {
+				local += " on " + host
+			}
+			targets = append(targets, fmt.Sprintf("%s: %d", local, count))
+		}
+		sort.Strings(targets)
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityCritical,
+			Source:   "scan",
+			Key:      "scan|synflood|total",
+			Message:  fmt.Sprintf("SYN flood: %d half-open connections from %d sources", total, len(perSource)),
+			Evidence: append([]string{fmt.Sprintf("threshold %d", d.opts.TotalSynRecv)}, targets...),
+		})
+	}
+	return alerts
+}
+
+func portList(ports map[int]bool, limit int) string {
+	sorted := make([]int, 0, len(ports))
+	for port := range ports {
+		sorted = append(sorted, port)
+	}
+	sort.Ints(sorted)
+
+	var parts []string
+	for i, port := range sorted {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("… %d more", len(sorted)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprint(port))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func hostNote(host string) string {
+	if host == "" {
+		return "on this host"
+	}
+	return "on " + host
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/metrics"
+)
+
+func runExporter(args []string) {
+	defaults := metrics.DefaultOptions()
+
+	fs := flag.NewFlagSet("exporter", flag.ExitOnError)
+	listen := fs.String("listen", ":9464", "address to serve /metrics on")
+	procRoot := fs.String("proc", "/proc", "procfs mount to read sockets and processes from")
+	labels := fs.String("labels", strings.Join(defaults.Labels, ","), "labels on stattui_connections: proto, state, process, local_port")
+	maxSeries := fs.Int("max-series", defaults.MaxSeries, "keep only the N most common process and local_port values, folding the rest into \"other\" (0 = unlimited)")
+	fs.Parse(args)
+
+	opts := defaults
+	var err error
+	if opts.Labels, err = metrics.ParseLabels(*labels); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.MaxSeries = *maxSeries
+
+	exporter, err := metrics.NewExporter(connections.NewCollector(*procRoot), opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Serving metrics on %s/metrics\n", *listen)
+	if err := http.ListenAndServe(*listen, exporter.Handler()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,40 @@
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Apply runs the commands in order and stops at the first failure. Rules
+// already inserted by earlier commands stay in place.
+func Apply(cmds []Command) error {
+	for i, cmd := range cmds {
+		if len(cmd) == 0 {
+			continue
+		}
+
+		var stderr bytes.Buffer
+		c := exec.Command(cmd[0], cmd[1:]...)
+		c.Stderr = &stderr
+
+		err := c.Run()
+		if err == nil {
+			continue
+		}
+
+		msg := strings.TrimSpace(stderr.String())
+		switch {
+		case errors.Is(err, exec.ErrNotFound):
+			return fmt.Errorf("%s is not installed", cmd[0])
+		case strings.Contains(msg, "Operation not permitted"), strings.Contains(msg, "Permission denied"):
+			return fmt.Errorf("permission denied: changing firewall rules needs root or CAP_NET_ADMIN")
+		case msg != "":
+			return fmt.Errorf("command %d of %d failed: %s: %s", i+1, len(cmds), cmd, msg)
+		}
+		return fmt.Errorf("command %d of %d failed: %s: %v", i+1, len(cmds), cmd, err)
+	}
+	return nil
+}
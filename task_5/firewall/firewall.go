@@ -0,0 +1,238 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+const (
+	Nftables = "nftables"
+	Iptables = "iptables"
+
+	// Tag marks every generated rule so it can be found and removed later.
+	Tag   = "stattui"
+	Table = "stattui"
+)
+
+var Backends = []string{Nftables, Iptables}
+
+// Matches are the ways a rule can select the connection's traffic.
+const (
+	MatchRemoteIP   = "remote-ip"
+	MatchRemoteCIDR = "remote-cidr"
+	MatchLocalPort  = "local-port"
+	MatchUID        = "uid"
+)
+
+var Matches = []string{MatchRemoteIP, MatchRemoteCIDR, MatchLocalPort, MatchUID}
+
+type Spec struct {
+	Backend    string
+	Allow      bool
+	Match      string
+	Connection models.ConnectionItem
+	// Prefix is the CIDR length for MatchRemoteCIDR; zero means /24 for
+	// IPv4 and /64 for IPv6.
+	Prefix int
+}
+
+// Command is one argv to run, without a shell.
+type Command []string
+
+func (c Command) String() string {
+	quoted := make([]string, len(c))
+	for i, arg := range c {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Generate returns the commands that block or allow the traffic the spec
+// selects. Address rules cover both directions; port rules only inbound
+// traffic and UID rules only outbound, since the owner match is only
+// meaningful for locally generated packets.
+func Generate(spec Spec) ([]Command, error) {
+	conn := spec.Connection
+	proto := strings.ToLower(strings.TrimSuffix(conn.Proto, "6"))
+	if proto != "tcp" && proto != "udp" {
+		return nil, fmt.Errorf("unsupported protocol %s", conn.Proto)
+	}
+
+	r := rule{proto: proto, allow: spec.Allow}
+	switch spec.Match {
+	case MatchRemoteIP, MatchRemoteCIDR:
+		ip := net.ParseIP(conn.RemoteIP)
+		if ip == nil || ip.IsUnspecified() {
+			return nil, fmt.Errorf("connection has no remote address")
+		}
+		r.addr = ip.String()
+		r.v6 = ip.To4() == nil
+		if spec.Match == MatchRemoteCIDR {
+			network, err := cidr(ip, spec.Prefix)
+			if err != nil {
+				return nil, err
+			}
+			r.addr = network
+		}
+	case MatchLocalPort:
+		if conn.LocalPort == 0 {
+			return nil, fmt.Errorf("connection has no local port")
+		}
+		r.port = conn.LocalPort
+	case MatchUID:
+		uid, err := strconv.Atoi(conn.UID)
+		if err != nil {
+			return nil, fmt.Errorf("connection has no owning UID")
+		}
+		r.uid = uid
+	default:
+		return nil, fmt.Errorf("unknown match %q", spec.Match)
+	}
+
+	switch spec.Backend {
+	case Nftables:
+		return r.nftables(), nil
+	case Iptables:
+		return r.iptables(), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q", spec.Backend)
+}
+
+// DefaultPrefix returns the CIDR length used for ip when Spec.Prefix is
+// zero, and the address length it can range up to.
+func DefaultPrefix(ip net.IP) (prefix, bits int) {
+	if ip.To4() != nil {
+		return 24, 32
+	}
+	return 64, 128
+}
+
+func cidr(ip net.IP, prefix int) (string, error) {
+	defaultPrefix, bits := DefaultPrefix(ip)
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if prefix == 0 {
+		prefix = defaultPrefix
+	}
+	if prefix < 1 || prefix > bits {
+		return "", fmt.Errorf("invalid prefix /%d", prefix)
+	}
+	mask := net.CIDRMask(prefix, bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String(), nil
+}
+
+type rule struct {
+	proto string
+	allow bool
+	addr  string
+	v6    bool
+	port  int
+	uid   int
+}
+
+func (r rule) nftables() []Command {
+	verdict := "drop"
+	if r.allow {
+		verdict = "accept"
+	}
+	comment := []string{"comment", `"` + Tag + `"`}
+
+	cmds := []Command{{"nft", "add", "table", "inet", Table}}
+	chain := func(name, hook string) Command {
+		// Priority -10 runs ahead of the distribution's filter chains.
+		return Command{"nft", "add", "chain", "inet", Table, name,
+			"{ type filter hook " + hook + " priority -10; policy accept; }"}
+	}
+	add := func(name string, match ...string) Command {
+		cmd := Command{"nft", "insert", "rule", "inet", Table, name}
+		cmd = append(cmd, match...)
+		cmd = append(cmd, verdict)
+		return append(cmd, comment...)
+	}
+
+	switch {
+	case r.addr != "":
+		family := "ip"
+		if r.v6 {
+			family = "ip6"
+		}
+		cmds = append(cmds,
+			chain("input", "input"),
+			chain("output", "output"),
+			add("input", family, "saddr", r.addr),
+			add("output", family, "daddr", r.addr),
+		)
+	case r.port != 0:
+		cmds = append(cmds,
+			chain("input", "input"),
+			add("input", r.proto, "dport", strconv.Itoa(r.port)),
+		)
+	default:
+		cmds = append(cmds,
+			chain("output", "output"),
+			add("output", "meta", "skuid", strconv.Itoa(r.uid)),
+		)
+	}
+	return cmds
+}
+
+func (r rule) iptables() []Command {
+	target := "DROP"
+	if r.allow {
+		target = "ACCEPT"
+	}
+	tools := []string{"iptables", "ip6tables"}
+	if r.addr != "" {
+		tools = tools[:1]
+		if r.v6 {
+			tools = []string{"ip6tables"}
+		}
+	}
+
+	var cmds []Command
+	for _, tool := range tools {
+		add := func(chain string, match ...string) Command {
+			cmd := Command{tool, "-I", chain}
+			cmd = append(cmd, match...)
+			return append(cmd, "-m", "comment", "--comment", Tag, "-j", target)
+		}
+
+		switch {
+		case r.addr != "":
+			cmds = append(cmds,
+				add("INPUT", "-s", r.addr),
+				add("OUTPUT", "-d", r.addr),
+			)
+		case r.port != 0:
+			cmds = append(cmds, add("INPUT", "-p", r.proto, "--dport", strconv.Itoa(r.port)))
+		default:
+			cmds = append(cmds, add("OUTPUT", "-m", "owner", "--uid-owner", strconv.Itoa(r.uid)))
+		}
+	}
+	return cmds
+}
+
+// Script renders the commands as a POSIX shell script.
+func Script(cmds []Command) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Generated by StatTUI. Rules are tagged \"" + Tag + "\".\nset -e\n\n")
+	for _, cmd := range cmds {
+		b.WriteString(cmd.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@+", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
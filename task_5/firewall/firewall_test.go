@@ -0,0 +1,202 @@
+package firewall
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+var (
+	connV4 = models.ConnectionItem{
+		Proto:      "TCP",
+		LocalIP:    "10.0.0.5",
+		LocalPort:  22,
+		RemoteIP:   "203.0.113.7",
+		RemotePort: 51000,
+		UID:        "1000",
+	}
+	connV6 = models.ConnectionItem{
+		Proto:      "TCP6",
+		LocalIP:    "2001:db8::1",
+		LocalPort:  443,
+		RemoteIP:   "2001:db8:aa:bb::7",
+		RemotePort: 40000,
+		UID:        "33",
+	}
+)
+
+const (
+	nftTable  = "nft add table inet stattui"
+	nftInput  = "nft add chain inet stattui input '{ type filter hook input priority -10; policy accept; }'"
+	nftOutput = "nft add chain inet stattui output '{ type filter hook output priority -10; policy accept; }'"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		want []string
+	}{
+		{
+			name: "nftables remote-ip v4",
+			spec: Spec{Backend: Nftables, Match: MatchRemoteIP, Connection: connV4},
+			want: []string{
+				nftTable, nftInput, nftOutput,
+				`nft insert rule inet stattui input ip saddr 203.0.113.7 drop comment '"stattui"'`,
+				`nft insert rule inet stattui output ip daddr 203.0.113.7 drop comment '"stattui"'`,
+			},
+		},
+		{
+			name: "nftables remote-ip v6",
+			spec: Spec{Backend: Nftables, Match: MatchRemoteIP, Connection: connV6},
+			want: []string{
+				nftTable, nftInput, nftOutput,
+				`nft insert rule inet stattui input ip6 saddr 2001:db8:aa:bb::7 drop comment '"stattui"'`,
+				`nft insert rule inet stattui output ip6 daddr 2001:db8:aa:bb::7 drop comment '"stattui"'`,
+			},
+		},
+		{
+			name: "iptables remote-ip v4",
+			spec: Spec{Backend: Iptables, Match: MatchRemoteIP, Connection: connV4},
+			want: []string{
+				"iptables -I INPUT -s 203.0.113.7 -m comment --comment stattui -j DROP",
+				"iptables -I OUTPUT -d 203.0.113.7 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "iptables remote-ip v6",
+			spec: Spec{Backend: Iptables, Match: MatchRemoteIP, Connection: connV6},
+			want: []string{
+				"ip6tables -I INPUT -s 2001:db8:aa:bb::7 -m comment --comment stattui -j DROP",
+				"ip6tables -I OUTPUT -d 2001:db8:aa:bb::7 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "nftables remote-cidr default /24",
+			spec: Spec{Backend: Nftables, Match: MatchRemoteCIDR, Connection: connV4},
+			want: []string{
+				nftTable, nftInput, nftOutput,
+				`nft insert rule inet stattui input ip saddr 203.0.113.0/24 drop comment '"stattui"'`,
+				`nft insert rule inet stattui output ip daddr 203.0.113.0/24 drop comment '"stattui"'`,
+			},
+		},
+		{
+			name: "iptables remote-cidr default /64",
+			spec: Spec{Backend: Iptables, Match: MatchRemoteCIDR, Connection: connV6},
+			want: []string{
+				"ip6tables -I INPUT -s 2001:db8:aa:bb::/64 -m comment --comment stattui -j DROP",
+				"ip6tables -I OUTPUT -d 2001:db8:aa:bb::/64 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "iptables remote-cidr explicit prefix",
+			spec: Spec{Backend: Iptables, Match: MatchRemoteCIDR, Connection: connV4, Prefix: 16},
+			want: []string{
+				"iptables -I INPUT -s 203.0.0.0/16 -m comment --comment stattui -j DROP",
+				"iptables -I OUTPUT -d 203.0.0.0/16 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "nftables local-port",
+			spec: Spec{Backend: Nftables, Match: MatchLocalPort, Connection: connV4},
+			want: []string{
+				nftTable, nftInput,
+				`nft insert rule inet stattui input tcp dport 22 drop comment '"stattui"'`,
+			},
+		},
+		{
+			name: "iptables local-port covers both families",
+			spec: Spec{Backend: Iptables, Match: MatchLocalPort, Connection: connV6},
+			want: []string{
+				"iptables -I INPUT -p tcp --dport 443 -m comment --comment stattui -j DROP",
+				"ip6tables -I INPUT -p tcp --dport 443 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "nftables uid",
+			spec: Spec{Backend: Nftables, Match: MatchUID, Connection: connV4},
+			want: []string{
+				nftTable, nftOutput,
+				`nft insert rule inet stattui output meta skuid 1000 drop comment '"stattui"'`,
+			},
+		},
+		{
+			name: "iptables uid",
+			spec: Spec{Backend: Iptables, Match: MatchUID, Connection: connV6},
+			want: []string{
+				"iptables -I OUTPUT -m owner --uid-owner 33 -m comment --comment stattui -j DROP",
+				"ip6tables -I OUTPUT -m owner --uid-owner 33 -m comment --comment stattui -j DROP",
+			},
+		},
+		{
+			name: "allow",
+			spec: Spec{Backend: Iptables, Allow: true, Match: MatchRemoteIP, Connection: connV4},
+			want: []string{
+				"iptables -I INPUT -s 203.0.113.7 -m comment --comment stattui -j ACCEPT",
+				"iptables -I OUTPUT -d 203.0.113.7 -m comment --comment stattui -j ACCEPT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := Generate(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(cmds))
+			for i, cmd := range cmds {
+				got[i] = cmd.String()
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	unspecified := connV4
+	unspecified.RemoteIP = "0.0.0.0"
+	noUID := connV4
+	noUID.UID = ""
+	udpLite := connV4
+	udpLite.Proto = "UDPLITE"
+
+	tests := []struct {
+		name string
+		spec Spec
+		want string
+	}{
+		{"unspecified remote", Spec{Backend: Nftables, Match: MatchRemoteIP, Connection: unspecified}, "connection has no remote address"},
+		{"unspecified remote cidr", Spec{Backend: Iptables, Match: MatchRemoteCIDR, Connection: unspecified}, "connection has no remote address"},
+		{"no uid", Spec{Backend: Nftables, Match: MatchUID, Connection: noUID}, "connection has no owning UID"},
+		{"unknown backend", Spec{Backend: "pf", Match: MatchRemoteIP, Connection: connV4}, `unknown backend "pf"`},
+		{"unknown match", Spec{Backend: Nftables, Match: "mac", Connection: connV4}, `unknown match "mac"`},
+		{"invalid prefix", Spec{Backend: Nftables, Match: MatchRemoteCIDR, Connection: connV4, Prefix: 33}, "invalid prefix /33"},
+		{"unsupported protocol", Spec{Backend: Nftables, Match: MatchRemoteIP, Connection: udpLite}, "unsupported protocol UDPLITE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(tt.spec)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Generate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScript(t *testing.T) {
+	cmds := []Command{
+		{"iptables", "-I", "INPUT", "-s", "203.0.113.7", "-j", "DROP"},
+		{"echo", "it's", ""},
+	}
+	want := "#!/bin/sh\n# Generated by StatTUI. Rules are tagged \"stattui\".\nset -e\n\n" +
+		"iptables -I INPUT -s 203.0.113.7 -j DROP\n" +
+		`echo 'it'\''s' ''` + "\n"
+	if got := Script(cmds); got != want {
+		t.Errorf("Script() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package geo
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Info is what the loaded databases know about one address. Country, city
+// and ASN databases each fill in their own fields.
+type Info struct {
+	Country string
+	City    string
+	ASN     uint
+	Org     string
+}
+
+// record covers the GeoIP2/GeoLite2 Country, City and ASN layouts.
+type record struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN uint   `maxminddb:"autonomous_system_number"`
+	Org string `maxminddb:"autonomous_system_organization"`
+}
+
+const maxCached = 65536
+
+type DB struct {
+	readers []*maxminddb.Reader
+
+	mu    sync.Mutex
+	cache map[string]Info
+}
+
+func Open(paths []string) (*DB, error) {
+	db := &DB{cache: make(map[string]Info)}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open GeoIP database %s: %v", path, err)
+		}
+		db.readers = append(db.readers, reader)
+	}
+	return db, nil
+}
+
+func (db *DB) Close() {
+	for _, reader := range db.readers {
+		reader.Close()
+	}
+}
+
+// Lookup returns what the databases know about ip. Results, including
+// misses, are cached for the life of the DB since the files do not change
+// underneath us.
+func (db *DB) Lookup(ip string) Info {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if info, ok := db.cache[ip]; ok {
+		return info
+	}
+
+	var info Info
+	if addr := net.ParseIP(ip); addr != nil {
+		for _, reader := range db.readers {
+			var r record
+			if err := reader.Lookup(addr, &r); err != nil {
+				continue
+			}
+			if r.Country.IsoCode != "" {
+				info.Country = r.Country.IsoCode
+			}
+			if name := r.City.Names["en"]; name != "" {
+				info.City = name
+			}
+			if r.ASN != 0 {
+				info.ASN = r.ASN
+				info.Org = r.Org
+			}
+		}
+	}
+
+	if len(db.cache) >= maxCached {
+		db.cache = make(map[string]Info)
+	}
+	db.cache[ip] = info
+	return info
+}
+
+// Enrich fills in location and ASN for connections to public addresses.
+func (db *DB) Enrich(conns []models.ConnectionItem) {
+	for i := range conns {
+		if connections.AddressScope(conns[i].RemoteIP) != "public" {
+			continue
+		}
+		info := db.Lookup(conns[i].RemoteIP)
+		conns[i].Country = info.Country
+		conns[i].City = info.City
+		conns[i].ASN = info.ASN
+		conns[i].ASOrg = info.Org
+	}
+}
+
+// Collector enriches every snapshot from the wrapped collector.
+type Collector struct {
+	inner models.Collector
+	db    *DB
+}
+
+func NewCollector(inner models.Collector, db *DB) *Collector {
+	return &Collector{inner: inner, db: db}
+}
+
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	snapshot, err := c.inner.Collect()
+	if err != nil {
+		return snapshot, err
+	}
+	c.db.Enrich(snapshot.Connections)
+	return snapshot, nil
+}
+
+func (c *Collector) Hostname() string {
+	return c.inner.Hostname()
+}
+
+func (c *Collector) Health() []models.HostHealth {
+	if reporter, ok := c.inner.(models.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type History struct {
+	mu     sync.Mutex
+	events []models.Event
+	limit  int
+}
+
+func New(limit int) *History {
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &History{limit: limit}
+}
+
+func (h *History) Add(events ...models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, events...)
+	if overflow := len(h.events) - h.limit; overflow > 0 {
+		h.events = append([]models.Event(nil), h.events[overflow:]...)
+	}
+}
+
+func (h *History) Since(since time.Time, limit int) []models.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var events []models.Event
+	for _, event := range h.events {
+		if event.Time.After(since) {
+			events = append(events, event)
+		}
+	}
+
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
+
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.events)
+}
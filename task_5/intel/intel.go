@@ -0,0 +1,269 @@
+package intel
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Indicator describes why an address range or domain is on a blocklist.
+type Indicator struct {
+	Source string `json:"source"`
+	Prefix string `json:"prefix,omitempty"`
+	Domain string `json:"domain,omitempty"`
+	Label  string `json:"label,omitempty"`
+}
+
+func (i Indicator) String() string {
+	s := i.Prefix
+	if i.Domain != "" {
+		s = i.Domain
+	}
+	s += " in " + i.Source
+	if i.Label != "" {
+		s += " (" + i.Label + ")"
+	}
+	return s
+}
+
+type Blocklist struct {
+	v4      trie
+	v6      trie
+	domains map[string]Indicator
+}
+
+func New() *Blocklist {
+	return &Blocklist{domains: make(map[string]Indicator)}
+}
+
+func (b *Blocklist) Len() int {
+	return b.v4.size + b.v6.size + len(b.domains)
+}
+
+// Domains is the number of domain indicators, which can only be matched
+// against reverse DNS names.
+func (b *Blocklist) Domains() int {
+	return len(b.domains)
+}
+
+// AddDomain lists a domain and everything below it.
+func (b *Blocklist) AddDomain(domain string, indicator Indicator) {
+	indicator.Domain = domain
+	b.domains[domain] = indicator
+}
+
+// LookupName matches a host name against the domain indicators, exactly or
+// as a subdomain.
+func (b *Blocklist) LookupName(name string) (Indicator, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for name != "" {
+		if indicator, ok := b.domains[name]; ok {
+			return indicator, true
+		}
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[i+1:]
+	}
+	return Indicator{}, false
+}
+
+func (b *Blocklist) Add(prefix netip.Prefix, indicator Indicator) {
+	prefix = prefix.Masked()
+	indicator.Prefix = prefix.String()
+	if prefix.Addr().Is4() {
+		b.v4.insert(prefix, indicator)
+	} else {
+		b.v6.insert(prefix, indicator)
+	}
+}
+
+func (b *Blocklist) Lookup(ip string) (Indicator, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Indicator{}, false
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return b.v4.lookup(addr)
+	}
+	return b.v6.lookup(addr)
+}
+
+// Load reads every file into one blocklist.
+func Load(paths []string) (*Blocklist, error) {
+	b := New()
+	for _, path := range paths {
+		if err := b.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// LoadFile picks a parser by extension: .json is read as a STIX 2 bundle,
+// .csv as an indicator export, anything else as a plain or FireHOL-style
+// list with one address or CIDR per line.
+func (b *Blocklist) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read blocklist: %v", err)
+	}
+	defer f.Close()
+
+	source := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = b.readSTIX(f, source)
+	case ".csv":
+		err = b.readCSV(f, source)
+	default:
+		err = b.readList(f, source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse blocklist %s: %v", path, err)
+	}
+	return nil
+}
+
+func parsePrefix(value string) (netip.Prefix, bool) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix, err == nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9_]([a-z0-9_-]*[a-z0-9])?\.)+[a-z]([a-z0-9-]*[a-z0-9])?$`)
+
+func parseDomain(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), "."))
+	value = strings.TrimPrefix(value, "*.")
+	return value, domainPattern.MatchString(value)
+}
+
+// add lists value as an address range or, failing that, a domain.
+func (b *Blocklist) add(value string, indicator Indicator) {
+	if prefix, ok := parsePrefix(value); ok {
+		b.Add(prefix, indicator)
+	} else if domain, ok := parseDomain(value); ok {
+		b.AddDomain(domain, indicator)
+	}
+}
+
+func (b *Blocklist) readList(r io.Reader, source string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		b.add(fields[0], Indicator{Source: source})
+	}
+	return scanner.Err()
+}
+
+var (
+	csvValueColumns = []string{"ip", "ip_address", "ipaddress", "dst_ip", "cidr", "domain", "hostname", "indicator", "value", "ioc"}
+	csvLabelColumns = []string{"description", "threat", "malware", "label", "tags", "name"}
+)
+
+func (b *Blocklist) readCSV(r io.Reader, source string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	valueCol, labelCol := column(header, csvValueColumns), column(header, csvLabelColumns)
+	if valueCol < 0 {
+		// No recognisable header: treat the first row as data.
+		valueCol = 0
+		b.add(header[0], Indicator{Source: source})
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if valueCol >= len(record) {
+			continue
+		}
+		indicator := Indicator{Source: source}
+		if labelCol >= 0 && labelCol < len(record) {
+			indicator.Label = strings.TrimSpace(record[labelCol])
+		}
+		b.add(record[valueCol], indicator)
+	}
+}
+
+func column(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+var stixAddress = regexp.MustCompile(`(?:ipv[46]-addr|domain-name):value\s*=\s*'([^']+)'`)
+
+type stixBundle struct {
+	Objects []struct {
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Pattern     string `json:"pattern"`
+	} `json:"objects"`
+}
+
+func (b *Blocklist) readSTIX(r io.Reader, source string) error {
+	var bundle stixBundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return err
+	}
+
+	for _, object := range bundle.Objects {
+		if object.Type != "indicator" {
+			continue
+		}
+		label := object.Name
+		if label == "" {
+			label = object.Description
+		}
+		for _, match := range stixAddress.FindAllStringSubmatch(object.Pattern, -1) {
+			b.add(match[1], Indicator{Source: source, Label: label})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package intel
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// Names looks up the reverse DNS name of an address without blocking, as
+// resolve.Resolver does.
+type Names interface {
+	Lookup(ip string) (string, bool)
+}
+
+// Matcher flags connections whose remote address is on a blocklist, or
+// whose reverse DNS name falls under a listed domain.
+type Matcher struct {
+	blocklist *Blocklist
+	names     Names
+}
+
+// NewMatcher takes names to match domain indicators with; without it only
+// addresses are matched and the domains are reported as unmatched.
+func NewMatcher(b *Blocklist, names Names) *Matcher {
+	return &Matcher{blocklist: b, names: names}
+}
+
+func (m *Matcher) lookup(conn models.ConnectionItem) (Indicator, string, bool) {
+	if indicator, ok := m.blocklist.Lookup(conn.RemoteIP); ok {
+		return indicator, "", true
+	}
+	if m.names == nil || m.blocklist.Domains() == 0 {
+		return Indicator{}, "", false
+	}
+	// The first sighting only queues the lookup; the match shows up on a
+	// later snapshot once the name is cached.
+	name, ok := m.names.Lookup(conn.RemoteIP)
+	if !ok {
+		return Indicator{}, "", false
+	}
+	indicator, ok := m.blocklist.LookupName(name)
+	return indicator, name, ok
+}
+
+func (m *Matcher) Observe(snapshot *models.Snapshot) []models.Alert {
+	var alerts []models.Alert
+	if m.names == nil && m.blocklist.Domains() > 0 {
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityInfo,
+			Source:   "intel",
+			Key:      "intel|domains",
+			Message:  fmt.Sprintf("%d domain indicators are not matched: they need reverse DNS lookups (-resolve)", m.blocklist.Domains()),
+		})
+	}
+	for _, conn := range snapshot.Connections {
+		if conn.RemotePort == 0 {
+			continue
+		}
+		indicator, name, ok := m.lookup(conn)
+		if !ok {
+			continue
+		}
+		remote := conn.Remote
+		if name != "" {
+			remote += " (" + name + ")"
+		}
+		flagged := conn
+		alerts = append(alerts, models.Alert{
+			Time:     snapshot.Time,
+			Severity: models.SeverityCritical,
+			Source:   "intel",
+			Key:      "intel|" + conn.Key(),
+			Message: fmt.Sprintf("%s %s → %s (%s, PID %s) matches %s",
+				conn.Proto, conn.Local, remote, conn.Process, conn.PID, indicator),
+			Connection: &flagged,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,55 @@
+package intel
+
+import "net/netip"
+
+// trie is a binary prefix tree over address bits. Lookups walk at most 32
+// or 128 nodes regardless of how many ranges are loaded.
+type trie struct {
+	root node
+	size int
+}
+
+type node struct {
+	children  [2]*node
+	indicator *Indicator
+}
+
+func (t *trie) insert(prefix netip.Prefix, indicator Indicator) {
+	addr := prefix.Addr()
+	n := &t.root
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := bitAt(addr, i)
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+	if n.indicator == nil {
+		t.size++
+	}
+	n.indicator = &indicator
+}
+
+// lookup returns the most specific range containing addr.
+func (t *trie) lookup(addr netip.Addr) (Indicator, bool) {
+	var match *Indicator
+	n := &t.root
+	for i := 0; n != nil; i++ {
+		if n.indicator != nil {
+			match = n.indicator
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		n = n.children[bitAt(addr, i)]
+	}
+	if match == nil {
+		return Indicator{}, false
+	}
+	return *match, true
+}
+
+func bitAt(addr netip.Addr, i int) int {
+	b := addr.AsSlice()
+	return int(b[i/8]>>(7-uint(i%8))) & 1
+}
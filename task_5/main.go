@@ -1,26 +1,266 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/mizerael/infsec_ssu/task_5/actions"
+	"github.com/mizerael/infsec_ssu/task_5/agent"
+	"github.com/mizerael/infsec_ssu/task_5/baseline"
+	"github.com/mizerael/infsec_ssu/task_5/config"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/conntrack"
+	"github.com/mizerael/infsec_ssu/task_5/detect"
+	"github.com/mizerael/infsec_ssu/task_5/geo"
+	"github.com/mizerael/infsec_ssu/task_5/intel"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/netif"
+	"github.com/mizerael/infsec_ssu/task_5/rates"
+	"github.com/mizerael/infsec_ssu/task_5/resolve"
+	"github.com/mizerael/infsec_ssu/task_5/rules"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
 	"github.com/mizerael/infsec_ssu/task_5/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	if _, err := os.Stat("/usr/bin/netstat"); err != nil {
-		if _, err := os.Stat("/bin/netstat"); err != nil {
-			fmt.Println("Error: netstat not found in standard paths")
-			fmt.Println("Please install netstat: sudo apt install net-tools")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "exporter":
+			runExporter(os.Args[2:])
+			return
+		case "serve":
+			runServer(os.Args[2:])
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "baseline":
+			runBaseline(os.Args[2:])
+			return
+		case "crosscheck":
+			runCrossCheck(os.Args[2:])
+			return
+		}
+	}
+
+	runTUI(os.Args[1:])
+}
+
+func runTUI(args []string) {
+	fs := flag.NewFlagSet("stattui", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file (default "+config.DefaultPath()+")")
+	themeName := fs.String("theme", "", "colour theme: auto, dark, light, high-contrast, mono or a custom theme name")
+	remote := fs.String("remote", "", "read connections from StatTUI agents (comma-separated host:port or unix:/path) instead of local /proc")
+	useTLS := fs.Bool("tls", false, "connect to the remote agent over TLS")
+	caFile := fs.String("ca", "", "CA bundle used to verify the agent certificate")
+	certFile := fs.String("cert", "", "client certificate for agents that require one")
+	keyFile := fs.String("key", "", "client private key")
+	serverName := fs.String("server-name", "", "expected agent certificate name (default: host from -remote)")
+	insecure := fs.Bool("insecure-skip-verify", false, "do not verify the agent certificate")
+	rulesPath := fs.String("rules", "", "JSON file with custom security rules, merged over the defaults")
+	blocklists := fs.String("blocklist", "", "comma-separated blocklist files (IP/CIDR lists, FireHOL netsets, STIX .json, .csv) to flag remote addresses against")
+	geoip := fs.String("geoip", "", "comma-separated MaxMind .mmdb files (country, city, ASN) to enrich remote addresses from")
+	resolveNames := fs.Bool("resolve", false, "look up reverse DNS names for remote addresses in the background")
+	dnsServer := fs.String("dns-server", "", "DNS server (host:port) for reverse lookups instead of the system resolver")
+	crossCheck := fs.Bool("crosscheck", false, "compare /proc/net, inet_diag and process fds every refresh to spot hidden sockets")
+	readOnly := fs.Bool("read-only", false, "disable signalling processes and destroying sockets")
+	dryRun := fs.Bool("dry-run", false, "confirm process and socket actions but only report what they would do")
+	applyFirewall := fs.Bool("apply-firewall", false, "let the firewall preview (F) apply the nftables/iptables rules it generates")
+	conntrackFlag := fs.Bool("conntrack", false, "read the netfilter conntrack table and match NATed flows to local sockets")
+	baselinePath := fs.String("baseline", "", "listener baseline to report drift against (default "+baseline.DefaultPath()+" if present)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %[1]s [flags]\n       %[1]s exporter [flags]\n       %[1]s serve [flags]\n       %[1]s agent [flags]\n       %[1]s baseline save|check [flags]\n       %[1]s crosscheck [flags]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *remote == "" {
+		if _, err := os.Stat("/usr/bin/netstat"); err != nil {
+			if _, err := os.Stat("/bin/netstat"); err != nil {
+				fmt.Println("Error: netstat not found in standard paths")
+				fmt.Println("Please install netstat: sudo apt install net-tools")
+				os.Exit(1)
+			}
+		}
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *themeName != "" {
+		cfg.Theme = *themeName
+	}
+	if *rulesPath != "" {
+		cfg.Rules = *rulesPath
+	}
+	if *baselinePath != "" {
+		cfg.Baseline = *baselinePath
+	}
+	if *resolveNames {
+		cfg.Resolve.Enabled = true
+	}
+	if *dnsServer != "" {
+		cfg.Resolve.Server = *dnsServer
+	}
+	if *crossCheck {
+		if *remote != "" {
+			fmt.Println("Error: -crosscheck only works on the local host")
+			os.Exit(1)
+		}
+		cfg.CrossCheck = true
+	}
+	if *conntrackFlag {
+		if *remote != "" {
+			fmt.Println("Error: -conntrack only works on the local host")
 			os.Exit(1)
 		}
+		cfg.Conntrack = true
+	}
+	if *readOnly {
+		cfg.ReadOnly = true
+	}
+	if *dryRun {
+		cfg.DryRun = true
+	}
+	if *applyFirewall {
+		cfg.ApplyFirewall = true
 	}
+	if *geoip != "" {
+		cfg.GeoIP = append(cfg.GeoIP, strings.Split(*geoip, ",")...)
+	}
+	if *blocklists != "" {
+		cfg.Blocklists = append(cfg.Blocklists, strings.Split(*blocklists, ",")...)
+	}
+
+	t, err := theme.Resolve(cfg.Theme, cfg.Themes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var collector models.Collector = connections.Default()
+	if *remote != "" {
+		opts := agent.ClientOptions{
+			TLS:                *useTLS,
+			CAFile:             *caFile,
+			CertFile:           *certFile,
+			KeyFile:            *keyFile,
+			ServerName:         *serverName,
+			InsecureSkipVerify: *insecure,
+		}
 
-	p := tea.NewProgram(ui.InitialModel(), tea.WithAltScreen())
+		addrs := strings.Split(*remote, ",")
+		if len(addrs) == 1 {
+			client, err := agent.NewClient(addrs[0], opts)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			client.Start()
+			collector = client
+		} else {
+			group, err := agent.NewGroup(addrs, opts)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			group.Start()
+			collector = group
+		}
+	}
+
+	var resolver *resolve.Resolver
+	var names intel.Names
+	if cfg.Resolve.Enabled {
+		resolver = resolve.New(cfg.Resolve)
+		names = resolver
+	}
+
+	pipeline, err := buildPipeline(collector, cfg, names)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	model := ui.InitialModel(t.Styles(), pipeline)
+	if resolver != nil {
+		model = model.WithResolver(resolver)
+	}
+	if local, ok := collector.(*connections.Collector); ok && !cfg.ReadOnly {
+		model = model.WithActions(actions.New(local, actions.Options{DryRun: cfg.DryRun, ApplyFirewall: cfg.ApplyFirewall}))
+	}
+
+	p := tea.NewProgram(model, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// buildPipeline layers the analysis stages the TUI shows on top of the raw
+// collector. Detectors run first so rate sampling sees the final snapshot.
+// names is nil unless reverse DNS is enabled.
+func buildPipeline(collector models.Collector, cfg *config.Config, names intel.Names) (models.Collector, error) {
+	ruleSet, err := rules.Load(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var detectors []detect.Detector
+	if local, ok := collector.(*connections.Collector); ok {
+		if cfg.CrossCheck {
+			detectors = append(detectors, connections.NewCrossChecker(local))
+		}
+		collector = netif.NewCollector(local, local.ProcRoot)
+		if cfg.Conntrack {
+			collector = conntrack.NewCollector(collector, local.ProcRoot)
+		}
+	}
+
+	if len(cfg.GeoIP) > 0 {
+		db, err := geo.Open(cfg.GeoIP)
+		if err != nil {
+			return nil, err
+		}
+		collector = geo.NewCollector(collector, db)
+	}
+
+	detectors = append(detectors,
+		detect.NewBacklogMonitor(cfg.Backlog),
+		detect.NewReverseShellDetector(),
+		detect.NewScanDetector(cfg.Scan),
+		rules.NewEngine(ruleSet),
+	)
+
+	if len(cfg.Blocklists) > 0 {
+		blocklist, err := intel.Load(cfg.Blocklists)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, intel.NewMatcher(blocklist, names))
+	}
+
+	baselinePath := cfg.Baseline
+	if baselinePath == "" {
+		if _, err := os.Stat(baseline.DefaultPath()); err == nil {
+			baselinePath = baseline.DefaultPath()
+		}
+	}
+	if baselinePath != "" {
+		b, err := baseline.Load(baselinePath)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, baseline.NewMonitor(b))
+	}
+
+	collector = detect.NewCollector(collector, detectors...)
+	return rates.NewCollector(collector), nil
+}
@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+var knownLabels = []string{"proto", "state", "process", "local_port"}
+
+const otherValue = "other"
+
+type Options struct {
+	Labels    []string
+	MaxSeries int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Labels:    []string{"proto", "state"},
+		MaxSeries: 50,
+	}
+}
+
+func ParseLabels(value string) ([]string, error) {
+	var labels []string
+	if strings.TrimSpace(value) == "" {
+		return labels, nil
+	}
+
+	for _, label := range strings.Split(value, ",") {
+		labels = append(labels, strings.TrimSpace(label))
+	}
+	if err := checkLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// checkLabels rejects unknown labels and repeats, which would produce
+// invalid exposition output.
+func checkLabels(labels []string) error {
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if !isKnownLabel(label) {
+			return fmt.Errorf("unknown label %q (available: %s)", label, strings.Join(knownLabels, ", "))
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate label %q", label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+func isKnownLabel(label string) bool {
+	for _, known := range knownLabels {
+		if label == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Exporter struct {
+	collector *connections.Collector
+	opts      Options
+
+	mu               sync.Mutex
+	collections      uint64
+	collectionErrors uint64
+}
+
+func NewExporter(collector *connections.Collector, opts Options) (*Exporter, error) {
+	if err := checkLabels(opts.Labels); err != nil {
+		return nil, err
+	}
+	return &Exporter{collector: collector, opts: opts}, nil
+}
+
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><h1>StatTUI exporter</h1><a href="/metrics">Metrics</a></body></html>`)
+	})
+	return mux
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := e.WriteMetrics(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (e *Exporter) WriteMetrics(out io.Writer) error {
+	snapshot, err := e.collector.Collect()
+
+	e.mu.Lock()
+	e.collections++
+	if err != nil || (snapshot != nil && len(snapshot.Errors) > 0) {
+		e.collectionErrors++
+	}
+	collections, collectionErrors := e.collections, e.collectionErrors
+	e.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+
+	if snapshot == nil {
+		snapshot = &models.Snapshot{}
+	}
+
+	success := 1
+	if err != nil {
+		success = 0
+	}
+
+	e.writeConnections(w, snapshot.Connections)
+	writeListenSockets(w, snapshot.Connections)
+
+	writeHeader(w, "stattui_collection_success", "gauge", "Whether the last collection of socket tables succeeded.")
+	fmt.Fprintf(w, "stattui_collection_success %d\n", success)
+
+	writeHeader(w, "stattui_collection_duration_seconds", "gauge", "Duration of the last collection in seconds.")
+	fmt.Fprintf(w, "stattui_collection_duration_seconds %g\n", snapshot.Duration.Seconds())
+
+	writeHeader(w, "stattui_collections_total", "counter", "Total number of collections performed.")
+	fmt.Fprintf(w, "stattui_collections_total %d\n", collections)
+
+	writeHeader(w, "stattui_collection_errors_total", "counter", "Total number of collections that reported errors.")
+	fmt.Fprintf(w, "stattui_collection_errors_total %d\n", collectionErrors)
+
+	own := snapshot.Ownership
+	writeHeader(w, "stattui_ownership_processes_scanned", "gauge", "Processes whose fd table was scanned during ownership resolution.")
+	fmt.Fprintf(w, "stattui_ownership_processes_scanned %d\n", own.ProcessesScanned)
+
+	writeHeader(w, "stattui_ownership_processes_unreadable", "gauge", "Processes whose fd table could not be read.")
+	fmt.Fprintf(w, "stattui_ownership_processes_unreadable %d\n", own.ProcessesDenied)
+
+	writeHeader(w, "stattui_ownership_socket_fds", "gauge", "Socket file descriptors found while scanning fd tables.")
+	fmt.Fprintf(w, "stattui_ownership_socket_fds %d\n", own.SocketFDs)
+
+	writeHeader(w, "stattui_ownership_sockets", "gauge", "Sockets by ownership resolution result.")
+	fmt.Fprintf(w, "stattui_ownership_sockets{result=\"resolved\"} %d\n", own.Resolved)
+	fmt.Fprintf(w, "stattui_ownership_sockets{result=\"unresolved\"} %d\n", own.Unresolved)
+
+	return w.Flush()
+}
+
+func (e *Exporter) writeConnections(w io.Writer, conns []models.ConnectionItem) {
+	allowed := make(map[string]map[string]bool)
+	for _, label := range e.opts.Labels {
+		if label == "process" || label == "local_port" {
+			allowed[label] = topValues(conns, label, e.opts.MaxSeries)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, conn := range conns {
+		values := make([]string, len(e.opts.Labels))
+		for i, label := range e.opts.Labels {
+			value := labelValue(conn, label)
+			if top, limited := allowed[label]; limited && !top[value] {
+				value = otherValue
+			}
+			values[i] = fmt.Sprintf("%s=\"%s\"", label, escapeLabel(value))
+		}
+		counts[strings.Join(values, ",")]++
+	}
+
+	writeHeader(w, "stattui_connections", "gauge", "Number of sockets in the TCP and UDP tables.")
+	for _, key := range sortedKeys(counts) {
+		if key == "" {
+			fmt.Fprintf(w, "stattui_connections %d\n", counts[key])
+		} else {
+			fmt.Fprintf(w, "stattui_connections{%s} %d\n", key, counts[key])
+		}
+	}
+}
+
+func writeListenSockets(w io.Writer, conns []models.ConnectionItem) {
+	counts := make(map[string]int)
+	for _, conn := range conns {
+		if conn.IsListening() {
+			counts[conn.Proto]++
+		}
+	}
+
+	writeHeader(w, "stattui_listen_sockets", "gauge", "Listening sockets by protocol.")
+	for _, proto := range sortedKeys(counts) {
+		fmt.Fprintf(w, "stattui_listen_sockets{proto=\"%s\"} %d\n", escapeLabel(proto), counts[proto])
+	}
+}
+
+func labelValue(conn models.ConnectionItem, label string) string {
+	switch label {
+	case "proto":
+		return conn.Proto
+	case "state":
+		return conn.State
+	case "process":
+		if conn.Process == "" {
+			return "unknown"
+		}
+		return conn.Process
+	case "local_port":
+		return strconv.Itoa(conn.LocalPort)
+	}
+	return ""
+}
+
+func topValues(conns []models.ConnectionItem, label string, limit int) map[string]bool {
+	counts := make(map[string]int)
+	for _, conn := range conns {
+		counts[labelValue(conn, label)]++
+	}
+
+	values := sortedKeys(counts)
+	sort.SliceStable(values, func(i, j int) bool {
+		return counts[values[i]] > counts[values[j]]
+	})
+
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+
+	top := make(map[string]bool, len(values))
+	for _, value := range values {
+		top[value] = true
+	}
+	return top
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
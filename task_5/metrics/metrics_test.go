@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "proto, state", want: []string{"proto", "state"}},
+		{value: "process,local_port", want: []string{"process", "local_port"}},
+		{value: "proto,proto", wantErr: true},
+		{value: "proto,bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLabels(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLabels(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ParseLabels(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewExporterRejectsDuplicateLabels(t *testing.T) {
+	_, err := NewExporter(connections.NewCollector("testdata/proc"), Options{Labels: []string{"state", "state"}})
+	if err == nil {
+		t.Fatal("NewExporter accepted duplicate labels")
+	}
+}
+
+func TestWriteMetricsFixture(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		want   []string
+		absent []string
+	}{
+		{
+			name: "default labels",
+			opts: DefaultOptions(),
+			want: []string{
+				`stattui_connections{proto="TCP",state="ESTABLISHED"} 2`,
+				`stattui_connections{proto="TCP",state="LISTEN"} 1`,
+				`stattui_connections{proto="TCP6",state="LISTEN"} 1`,
+				`stattui_connections{proto="UDP",state="LISTEN"} 1`,
+				`stattui_listen_sockets{proto="TCP"} 1`,
+				`stattui_listen_sockets{proto="TCP6"} 1`,
+				`stattui_listen_sockets{proto="UDP"} 1`,
+				`stattui_collection_success 1`,
+				`stattui_collection_errors_total 0`,
+				`stattui_ownership_processes_scanned 2`,
+				`stattui_ownership_socket_fds 4`,
+				`stattui_ownership_sockets{result="resolved"} 4`,
+				`stattui_ownership_sockets{result="unresolved"} 1`,
+			},
+		},
+		{
+			name: "process and port",
+			opts: Options{Labels: []string{"process", "local_port"}},
+			want: []string{
+				`stattui_connections{process="sshd",local_port="22"} 3`,
+				`stattui_connections{process="nginx",local_port="80"} 1`,
+				`stattui_connections{process="unknown",local_port="53"} 1`,
+			},
+		},
+		{
+			name: "series limit",
+			opts: Options{Labels: []string{"process"}, MaxSeries: 1},
+			want: []string{
+				`stattui_connections{process="sshd"} 3`,
+				`stattui_connections{process="other"} 2`,
+			},
+			absent: []string{`process="nginx"`},
+		},
+		{
+			name: "no labels",
+			opts: Options{},
+			want: []string{"stattui_connections 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := NewExporter(connections.NewCollector("testdata/proc"), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := exporter.WriteMetrics(&out); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := make(map[string]bool)
+			for _, line := range strings.Split(out.String(), "\n") {
+				lines[line] = true
+			}
+			for _, want := range tt.want {
+				if !lines[want] {
+					t.Errorf("missing %q in output:\n%s", want, out.String())
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(out.String(), absent) {
+					t.Errorf("unexpected %q in output", absent)
+				}
+			}
+		})
+	}
+}
@@ -2,20 +2,172 @@ package models
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/mizerael/infsec_ssu/task_5/resolve"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
 )
 
 type ConnectionItem struct {
-	Proto   string
-	Local   string
-	Remote  string
-	State   string
-	PID     string
-	Process string
+	Proto        string `json:"proto"`
+	Local        string `json:"local"`
+	Remote       string `json:"remote"`
+	State        string `json:"state"`
+	PID          string `json:"pid"`
+	Process      string `json:"process"`
+	LocalIP      string `json:"local_ip"`
+	LocalPort    int    `json:"local_port"`
+	RemoteIP     string `json:"remote_ip"`
+	RemotePort   int    `json:"remote_port"`
+	Inode        string `json:"inode"`
+	UID          string `json:"uid"`
+	User         string `json:"user"`
+	Host         string `json:"host,omitempty"`
+	PeerHost     string `json:"peer_host,omitempty"`
+	PeerPID      string `json:"peer_pid,omitempty"`
+	PeerProc     string `json:"peer_process,omitempty"`
+	Exe          string `json:"exe,omitempty"`
+	ExeDeleted   bool   `json:"exe_deleted,omitempty"`
+	ExeReplaced  bool   `json:"exe_replaced,omitempty"`
+	ExeUnsafeDir bool   `json:"exe_unsafe_dir,omitempty"`
+	ExeSHA256    string `json:"exe_sha256,omitempty"`
+
+	Stdio []StdioFD `json:"stdio,omitempty"`
+
+	LocalService  string `json:"local_service,omitempty"`
+	RemoteService string `json:"remote_service,omitempty"`
+
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+
+	TCP *TCPMetrics `json:"tcp_info,omitempty"`
+
+	Conntrack *ConntrackEntry `json:"conntrack,omitempty"`
+
+	Iface    string `json:"iface,omitempty"`
+	NextHop  string `json:"next_hop,omitempty"`
+	NeighMAC string `json:"neigh_mac,omitempty"`
+
+	TxQueue int `json:"tx_queue"`
+	RxQueue int `json:"rx_queue"`
+
+	TxRate      float64   `json:"tx_rate,omitempty"`
+	RxRate      float64   `json:"rx_rate,omitempty"`
+	RateHistory []float64 `json:"-"`
+}
+
+// StdioFD records a process that has the socket as stdin, stdout or
+// stderr.
+type StdioFD struct {
+	PID     string `json:"pid"`
+	FD      int    `json:"fd"`
+	Process string `json:"process"`
+}
+
+// Interface holds a network interface's counters from /proc/net/dev.
+type Interface struct {
+	Name        string    `json:"name"`
+	RxBytes     uint64    `json:"rx_bytes"`
+	RxPackets   uint64    `json:"rx_packets"`
+	RxErrors    uint64    `json:"rx_errors"`
+	RxDropped   uint64    `json:"rx_dropped"`
+	TxBytes     uint64    `json:"tx_bytes"`
+	TxPackets   uint64    `json:"tx_packets"`
+	TxErrors    uint64    `json:"tx_errors"`
+	TxDropped   uint64    `json:"tx_dropped"`
+	RxRate      float64   `json:"rx_rate,omitempty"`
+	TxRate      float64   `json:"tx_rate,omitempty"`
+	RateHistory []float64 `json:"-"`
+}
+
+// ConntrackTuple is one direction of a tracked flow.
+type ConntrackTuple struct {
+	Src   string `json:"src"`
+	Dst   string `json:"dst"`
+	SPort int    `json:"sport"`
+	DPort int    `json:"dport"`
+}
+
+func (t ConntrackTuple) String() string {
+	return fmt.Sprintf("%s → %s", net.JoinHostPort(t.Src, strconv.Itoa(t.SPort)), net.JoinHostPort(t.Dst, strconv.Itoa(t.DPort)))
+}
+
+// ConntrackEntry is a netfilter connection tracking entry. Original is the
+// flow as first seen, Reply the flow answers are expected on; when NAT
+// rewrote the connection the two no longer mirror each other.
+type ConntrackEntry struct {
+	Family   string         `json:"family"`
+	Proto    string         `json:"proto"`
+	State    string         `json:"state,omitempty"`
+	Timeout  time.Duration  `json:"timeout_ns"`
+	Original ConntrackTuple `json:"original"`
+	Reply    ConntrackTuple `json:"reply"`
+	Flags    []string       `json:"flags,omitempty"`
+	Mark     uint32         `json:"mark"`
+	Zone     uint16         `json:"zone,omitempty"`
+	PID      string         `json:"pid,omitempty"`
+	Process  string         `json:"process,omitempty"`
+}
+
+// SNAT reports whether the source of the original flow was rewritten.
+func (e ConntrackEntry) SNAT() bool {
+	return e.Reply.Dst != e.Original.Src || e.Reply.DPort != e.Original.SPort
+}
+
+// DNAT reports whether the destination of the original flow was rewritten.
+func (e ConntrackEntry) DNAT() bool {
+	return e.Reply.Src != e.Original.Dst || e.Reply.SPort != e.Original.DPort
+}
+
+func (e ConntrackEntry) NAT() string {
+	switch {
+	case e.SNAT() && e.DNAT():
+		return "SNAT+DNAT"
+	case e.SNAT():
+		return "SNAT"
+	case e.DNAT():
+		return "DNAT"
+	}
+	return ""
+}
+
+type ProcessRate struct {
+	Host        string    `json:"host,omitempty"`
+	PID         string    `json:"pid"`
+	Process     string    `json:"process"`
+	Connections int       `json:"connections"`
+	TxRate      float64   `json:"tx_rate"`
+	RxRate      float64   `json:"rx_rate"`
+	History     []float64 `json:"-"`
+}
+
+type TCPMetrics struct {
+	RTT           time.Duration `json:"rtt_ns"`
+	RTTVar        time.Duration `json:"rtt_var_ns"`
+	Cwnd          uint32        `json:"cwnd"`
+	Retransmits   uint32        `json:"total_retrans"`
+	SegsOut       uint32        `json:"segs_out"`
+	BytesSent     uint64        `json:"bytes_sent"`
+	BytesAcked    uint64        `json:"bytes_acked"`
+	BytesReceived uint64        `json:"bytes_received"`
+	DeliveryRate  uint64        `json:"delivery_rate"`
+	LastSend      time.Duration `json:"last_send_ns"`
+	LastReceive   time.Duration `json:"last_recv_ns"`
+}
+
+func (t *TCPMetrics) RetransmitRatio() float64 {
+	if t == nil || t.SegsOut == 0 {
+		return 0
+	}
+	return float64(t.Retransmits) / float64(t.SegsOut)
 }
 
 func (c ConnectionItem) Title() string {
@@ -23,11 +175,231 @@ func (c ConnectionItem) Title() string {
 }
 
 func (c ConnectionItem) Description() string {
-	return fmt.Sprintf("State: %-13s| PID: %-8s | Process: %s", c.State, c.PID, c.Process)
+	return fmt.Sprintf("State: %-13s| PID: %-8s | User: %-10s | Process: %s", c.State, c.PID, c.User, c.Process)
+}
+
+func (c ConnectionItem) IsListening() bool {
+	return strings.Contains(strings.ToUpper(c.State), "LISTEN")
+}
+
+func (c ConnectionItem) IsRootOwned() bool {
+	return c.UID == "0"
+}
+
+func (c ConnectionItem) IsPrivilegedPort() bool {
+	return c.LocalPort > 0 && c.LocalPort < 1024
+}
+
+func (c ConnectionItem) HasBacklog() bool {
+	return strings.HasPrefix(c.Proto, "TCP") && c.IsListening() && c.TxQueue > 0
+}
+
+func (c ConnectionItem) BacklogFill() float64 {
+	if !c.HasBacklog() {
+		return 0
+	}
+	return float64(c.RxQueue) / float64(c.TxQueue)
 }
 
 func (c ConnectionItem) FilterValue() string {
-	return fmt.Sprintf("%s %s %s %s", c.Proto, c.Local, c.Remote, c.State)
+	value := fmt.Sprintf("%s %s %s %s", c.Proto, c.Local, c.Remote, c.State)
+	for _, field := range c.FilterFields() {
+		value += " " + field
+	}
+	return value
+}
+
+// FilterFields are the "key:value" tokens the filter language matches
+// against. Spaces in values become underscores so each stays one token.
+func (c ConnectionItem) FilterFields() []string {
+	var fields []string
+	add := func(key, value string) {
+		if value != "" {
+			fields = append(fields, key+":"+strings.ReplaceAll(value, " ", "_"))
+		}
+	}
+	if c.LocalPort != 0 {
+		add("port", fmt.Sprintf("%d", c.LocalPort))
+	}
+	if c.RemotePort != 0 {
+		add("port", fmt.Sprintf("%d", c.RemotePort))
+	}
+	add("service", c.LocalService)
+	add("service", c.RemoteService)
+	add("exe", c.Exe)
+	if c.ExeDeleted {
+		add("exe", "deleted")
+	}
+	if c.ExeReplaced {
+		add("exe", "replaced")
+	}
+	if c.ExeUnsafeDir {
+		add("exe", "unsafe-dir")
+	}
+	add("sha256", c.ExeSHA256)
+	add("iface", c.Iface)
+	add("country", c.Country)
+	add("city", c.City)
+	if c.ASN != 0 {
+		add("asn", fmt.Sprintf("%d", c.ASN))
+	}
+	add("org", c.ASOrg)
+	return fields
+}
+
+func (c ConnectionItem) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", c.Host, c.Proto, c.Local, c.Remote, c.Inode)
+}
+
+type OwnershipStats struct {
+	ProcessesScanned int `json:"processes_scanned"`
+	ProcessesDenied  int `json:"processes_denied"`
+	SocketFDs        int `json:"socket_fds"`
+	Resolved         int `json:"resolved"`
+	Unresolved       int `json:"unresolved"`
+}
+
+type Snapshot struct {
+	Host        string           `json:"host"`
+	Time        time.Time        `json:"time"`
+	Connections []ConnectionItem `json:"connections"`
+	Ownership   OwnershipStats   `json:"ownership"`
+	Errors      []string         `json:"errors,omitempty"`
+	Duration    time.Duration    `json:"duration_ns"`
+	Processes   []ProcessRate    `json:"processes,omitempty"`
+	Sockstat    []SockstatEntry  `json:"sockstat,omitempty"`
+	Counters    []KernelCounter  `json:"counters,omitempty"`
+	Alerts      []Alert          `json:"alerts,omitempty"`
+	Conntrack   []ConntrackEntry `json:"conntrack,omitempty"`
+	Interfaces  []Interface      `json:"interfaces,omitempty"`
+}
+
+type Counter struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+type SockstatEntry struct {
+	Host     string    `json:"host,omitempty"`
+	Proto    string    `json:"proto"`
+	Counters []Counter `json:"counters"`
+}
+
+func (e SockstatEntry) String() string {
+	parts := make([]string, 0, len(e.Counters))
+	for _, c := range e.Counters {
+		parts = append(parts, fmt.Sprintf("%s %d", c.Name, c.Value))
+	}
+	return e.Proto + ": " + strings.Join(parts, " ")
+}
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+type Alert struct {
+	Time       time.Time       `json:"time"`
+	Severity   string          `json:"severity"`
+	Source     string          `json:"source"`
+	Key        string          `json:"key"`
+	Message    string          `json:"message"`
+	Evidence   []string        `json:"evidence,omitempty"`
+	Connection *ConnectionItem `json:"connection,omitempty"`
+}
+
+type KernelCounter struct {
+	Host  string `json:"host,omitempty"`
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+type SnapshotDiff struct {
+	Host      string           `json:"host"`
+	Time      time.Time        `json:"time"`
+	Added     []ConnectionItem `json:"added,omitempty"`
+	Updated   []ConnectionItem `json:"updated,omitempty"`
+	Removed   []string         `json:"removed,omitempty"`
+	Ownership OwnershipStats   `json:"ownership"`
+	Errors    []string         `json:"errors,omitempty"`
+	Duration  time.Duration    `json:"duration_ns"`
+	Sockstat  []SockstatEntry  `json:"sockstat,omitempty"`
+	Counters  []KernelCounter  `json:"counters,omitempty"`
+}
+
+type Collector interface {
+	Collect() (*Snapshot, error)
+	Hostname() string
+}
+
+type HostHealth struct {
+	Host        string    `json:"host"`
+	Addr        string    `json:"addr"`
+	LastSeen    time.Time `json:"last_seen"`
+	Error       string    `json:"error,omitempty"`
+	Connections int       `json:"connections"`
+}
+
+type HealthReporter interface {
+	Health() []HostHealth
+}
+
+// ActionRunner carries out the process and socket actions offered by the
+// TUI. A model without one is read-only.
+type ActionRunner interface {
+	DryRun() bool
+	Owners(conn ConnectionItem) []string
+	Signal(pids []string, signal string) (string, error)
+	Destroy(conn ConnectionItem) (string, error)
+	CanApplyFirewall() bool
+	ApplyFirewall(commands [][]string) (string, error)
+}
+
+type ActionKind int
+
+const (
+	ActionSignal ActionKind = iota
+	ActionDestroy
+)
+
+// FirewallDraft is the rule being built in the firewall preview. Backend
+// and Match index firewall.Backends and firewall.Matches.
+type FirewallDraft struct {
+	Connection ConnectionItem
+	Backend    int
+	Match      int
+	// Prefix is the remote-cidr length; zero means /24 or /64.
+	Prefix  int
+	Allow   bool
+	Confirm bool
+	Status  string
+}
+
+// PendingAction is an action waiting for the user to confirm it.
+type PendingAction struct {
+	Kind       ActionKind
+	Connection ConnectionItem
+	PIDs       []string
+	Signal     int
+}
+
+type EventKind string
+
+const (
+	EventOpened EventKind = "opened"
+	EventClosed EventKind = "closed"
+	EventAlert  EventKind = "alert"
+)
+
+type Event struct {
+	Time       time.Time      `json:"time"`
+	Kind       EventKind      `json:"kind"`
+	Connection ConnectionItem `json:"connection"`
+	Message    string         `json:"message,omitempty"`
+	Severity   string         `json:"severity,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Evidence   []string       `json:"evidence,omitempty"`
 }
 
 type AppModel struct {
@@ -44,6 +416,63 @@ type AppModel struct {
 	InputMode       bool
 	IntervalInput   textinput.Model
 	StatusMsg       string
+	Styles          theme.Styles
+	Collector       Collector
+	Host            string
+	Hosts           []string
+	HostTab         int
+	AllConnections  []ConnectionItem
+	Health          []HostHealth
+	ShowDetails     bool
+	ShowMetrics     bool
+	SortByRate      bool
+	ProcessRates    []ProcessRate
+	ActiveView      ViewMode
+	Snapshot        *Snapshot
+	CounterPrev     map[string]int64
+	CounterDeltas   map[string]int64
+	CounterTime     time.Time
+	Alerts          []Alert
+	Events          []Event
+	Resolver        *resolve.Resolver
+	Names           map[string]string
+	ShowNames       bool
+	ShowServices    bool
+	Actions         ActionRunner
+	Confirm         *PendingAction
+	Firewall        *FirewallDraft
+}
+
+type ViewMode int
+
+const (
+	ViewConnections ViewMode = iota
+	ViewDashboard
+	ViewCounters
+	ViewEvents
+	ViewConntrack
+	ViewInterfaces
+	viewCount
+)
+
+func (v ViewMode) String() string {
+	switch v {
+	case ViewDashboard:
+		return "Dashboard"
+	case ViewCounters:
+		return "Kernel counters"
+	case ViewEvents:
+		return "Events"
+	case ViewConntrack:
+		return "Conntrack"
+	case ViewInterfaces:
+		return "Interfaces"
+	}
+	return "Connections"
+}
+
+func (v ViewMode) Next() ViewMode {
+	return (v + 1) % viewCount
 }
 
 type KeyMap struct {
@@ -53,12 +482,38 @@ type KeyMap struct {
 	ChangeInterval key.Binding
 	ToggleHelp     key.Binding
 	Netstat        key.Binding
+	NextHost       key.Binding
+	PrevHost       key.Binding
+	ToggleDetails  key.Binding
+	ToggleMetrics  key.Binding
+	SortByRate     key.Binding
+	ToggleNames    key.Binding
+	ToggleServices key.Binding
+	Kill           key.Binding
+	Destroy        key.Binding
+	Firewall       key.Binding
+	NextView       key.Binding
 	Quit           key.Binding
 }
 
 type ConnectionsLoadedMsg struct {
 	Connections []ConnectionItem
 	FilterState string
+	Host        string
+	Health      []HostHealth
+	Processes   []ProcessRate
+	Snapshot    *Snapshot
+	Alerts      []Alert
+}
+
+type NameResolvedMsg struct {
+	IP   string
+	Name string
+}
+
+type ActionDoneMsg struct {
+	Status string
+	Err    string
 }
 
 type ConnectionErrorMsg string
@@ -86,6 +541,50 @@ func DefaultKeys() KeyMap {
 			key.WithKeys("?"),
 			key.WithHelp("?", "toggle help"),
 		),
+		NextHost: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next host"),
+		),
+		PrevHost: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous host"),
+		),
+		ToggleDetails: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "toggle detail pane"),
+		),
+		ToggleMetrics: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "toggle TCP metric columns"),
+		),
+		SortByRate: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort by bandwidth"),
+		),
+		ToggleNames: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "toggle host names"),
+		),
+		ToggleServices: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "toggle service names"),
+		),
+		Kill: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", "signal owning process"),
+		),
+		Destroy: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "destroy TCP socket"),
+		),
+		Firewall: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "firewall rules"),
+		),
+		NextView: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch view"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
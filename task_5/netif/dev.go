@@ -0,0 +1,54 @@
+package netif
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// ReadDev parses the per-interface counters in /proc/net/dev.
+func ReadDev(procRoot string) ([]models.Interface, error) {
+	file, err := os.Open(filepath.Join(procRoot, "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var ifaces []models.Interface
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 16 {
+			continue
+		}
+
+		values := make([]uint64, len(fields))
+		for i, field := range fields {
+			values[i], _ = strconv.ParseUint(field, 10, 64)
+		}
+		ifaces = append(ifaces, models.Interface{
+			Name:      strings.TrimSpace(name),
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDropped: values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDropped: values[11],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("net/dev: %v", err)
+	}
+	return ifaces, nil
+}
@@ -0,0 +1,43 @@
+package netif
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const atfCom = 0x2
+
+type Neighbour struct {
+	IP    string
+	MAC   string
+	Iface string
+}
+
+// ReadARP parses the IPv4 neighbour table in /proc/net/arp, skipping
+// entries that never resolved.
+func ReadARP(procRoot string) ([]Neighbour, error) {
+	file, err := os.Open(filepath.Join(procRoot, "net", "arp"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var neighbours []Neighbour
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[2], 0, 32)
+		if err != nil || flags&atfCom == 0 {
+			continue
+		}
+		neighbours = append(neighbours, Neighbour{IP: fields[0], MAC: fields[3], Iface: fields[5]})
+	}
+	return neighbours, scanner.Err()
+}
@@ -0,0 +1,120 @@
+//go:build linux
+
+package netif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sizeofNdMsg = 12
+	ndaDst      = 1
+	ndaLladdr   = 2
+)
+
+// DumpNeighbours6 reads the IPv6 neighbour table over rtnetlink; /proc has
+// no equivalent of /proc/net/arp for IPv6.
+func DumpNeighbours6() ([]Neighbour, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("rtnetlink socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	tv := unix.NsecToTimeval(int64(2 * time.Second))
+	unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv)
+
+	req := make([]byte, unix.SizeofNlMsghdr+sizeofNdMsg)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], unix.RTM_GETNEIGH)
+	binary.NativeEndian.PutUint16(req[6:8], unix.NLM_F_REQUEST|unix.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	req[unix.SizeofNlMsghdr] = unix.AF_INET6
+
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("rtnetlink send: %v", err)
+	}
+
+	names := make(map[int]string)
+	var neighbours []Neighbour
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("rtnetlink recv: %v", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("rtnetlink parse: %v", err)
+		}
+
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case unix.NLMSG_DONE:
+				return neighbours, nil
+			case unix.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(msg.Data[0:4])); errno != 0 {
+						return nil, fmt.Errorf("rtnetlink: %v", unix.Errno(-errno))
+					}
+				}
+				return neighbours, nil
+			case unix.RTM_NEWNEIGH:
+			default:
+				continue
+			}
+
+			if neighbour, ok := parseNeighbour(msg.Data, names); ok {
+				neighbours = append(neighbours, neighbour)
+			}
+		}
+	}
+}
+
+func parseNeighbour(data []byte, names map[int]string) (Neighbour, bool) {
+	if len(data) < sizeofNdMsg {
+		return Neighbour{}, false
+	}
+	ifindex := int(int32(binary.NativeEndian.Uint32(data[4:8])))
+	state := binary.NativeEndian.Uint16(data[8:10])
+	if state&(unix.NUD_INCOMPLETE|unix.NUD_FAILED|unix.NUD_NOARP) != 0 {
+		return Neighbour{}, false
+	}
+
+	var neighbour Neighbour
+	attrs := data[sizeofNdMsg:]
+	for len(attrs) >= 4 {
+		length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		if length < 4 || length > len(attrs) {
+			break
+		}
+		value := attrs[4:length]
+		switch binary.NativeEndian.Uint16(attrs[2:4]) {
+		case ndaDst:
+			neighbour.IP = net.IP(value).String()
+		case ndaLladdr:
+			neighbour.MAC = net.HardwareAddr(value).String()
+		}
+
+		aligned := (length + 3) &^ 3
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+
+	if _, ok := names[ifindex]; !ok {
+		if iface, err := net.InterfaceByIndex(ifindex); err == nil {
+			names[ifindex] = iface.Name
+		}
+	}
+	neighbour.Iface = names[ifindex]
+	return neighbour, neighbour.IP != "" && neighbour.MAC != ""
+}
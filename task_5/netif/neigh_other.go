@@ -0,0 +1,9 @@
+//go:build !linux
+
+package netif
+
+import "fmt"
+
+func DumpNeighbours6() ([]Neighbour, error) {
+	return nil, fmt.Errorf("rtnetlink is only available on Linux")
+}
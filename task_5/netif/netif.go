@@ -0,0 +1,97 @@
+package netif
+
+import (
+	"net"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// Enrich sets the egress interface, next hop and next-hop MAC on each
+// connection with a remote peer. The MAC is the peer's own for on-link
+// peers and the gateway's otherwise.
+func Enrich(conns []models.ConnectionItem, routes Table, neighbours []Neighbour) {
+	macs := make(map[string]string, len(neighbours))
+	for _, n := range neighbours {
+		macs[n.Iface+"|"+n.IP] = n.MAC
+	}
+
+	for i := range conns {
+		conn := &conns[i]
+		remote := net.ParseIP(conn.RemoteIP)
+		if remote == nil || remote.IsUnspecified() {
+			continue
+		}
+		if remote.IsLoopback() || conn.RemoteIP == conn.LocalIP {
+			conn.Iface = "lo"
+			continue
+		}
+
+		route, ok := routes.Lookup(remote)
+		if !ok {
+			continue
+		}
+		conn.Iface = route.Iface
+
+		hop := remote.String()
+		if !route.OnLink() {
+			hop = route.Gateway.String()
+			conn.NextHop = hop
+		}
+		conn.NeighMAC = macs[route.Iface+"|"+hop]
+	}
+}
+
+// Collector adds interface counters and per-connection routing context to
+// each snapshot of the local host. Missing tables are reported in the
+// snapshot, not as an error.
+type Collector struct {
+	inner    models.Collector
+	procRoot string
+}
+
+func NewCollector(inner models.Collector, procRoot string) *Collector {
+	return &Collector{inner: inner, procRoot: procRoot}
+}
+
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	snapshot, err := c.inner.Collect()
+	if err != nil {
+		return snapshot, err
+	}
+
+	ifaces, err := ReadDev(c.procRoot)
+	if err != nil {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+	}
+	snapshot.Interfaces = ifaces
+
+	routes, err := ReadRoutes(c.procRoot)
+	if err != nil {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+		return snapshot, nil
+	}
+
+	neighbours, err := ReadARP(c.procRoot)
+	if err != nil {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+	}
+	if c.procRoot == "/proc" {
+		if v6, err := DumpNeighbours6(); err == nil {
+			neighbours = append(neighbours, v6...)
+		}
+	}
+
+	Enrich(snapshot.Connections, routes, neighbours)
+	return snapshot, nil
+}
+
+func (c *Collector) Hostname() string {
+	return c.inner.Hostname()
+}
+
+func (c *Collector) Health() []models.HostHealth {
+	if reporter, ok := c.inner.(models.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package netif
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	rtfUp     = 0x1
+	rtfReject = 0x200
+	rtfLocal  = 0x80000000
+)
+
+type Route struct {
+	Iface   string
+	Dst     *net.IPNet
+	Gateway net.IP
+	Metric  uint32
+}
+
+// OnLink reports whether destinations of the route are reached directly
+// rather than through a gateway.
+func (r Route) OnLink() bool {
+	return r.Gateway == nil || r.Gateway.IsUnspecified()
+}
+
+type Table []Route
+
+// ReadRoutes loads the main routing tables from /proc/net/route and
+// /proc/net/ipv6_route. Either file may be missing.
+func ReadRoutes(procRoot string) (Table, error) {
+	v4, err4 := readRouteFile(filepath.Join(procRoot, "net", "route"), parseRoute4)
+	v6, err6 := readRouteFile(filepath.Join(procRoot, "net", "ipv6_route"), parseRoute6)
+	if err4 != nil && err6 != nil {
+		return nil, err4
+	}
+	return append(v4, v6...), nil
+}
+
+// Lookup picks the route the kernel would use for ip: the longest matching
+// prefix, then the lowest metric.
+func (t Table) Lookup(ip net.IP) (Route, bool) {
+	var best Route
+	bestLen := -1
+	for _, route := range t {
+		if !route.Dst.Contains(ip) {
+			continue
+		}
+		ones, _ := route.Dst.Mask.Size()
+		if ones > bestLen || ones == bestLen && route.Metric < best.Metric {
+			best, bestLen = route, ones
+		}
+	}
+	return best, bestLen >= 0
+}
+
+func readRouteFile(filename string, parse func([]string) (Route, bool)) (Table, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var table Table
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if route, ok := parse(strings.Fields(scanner.Text())); ok {
+			table = append(table, route)
+		}
+	}
+	return table, scanner.Err()
+}
+
+// parseRoute4 reads a /proc/net/route line. Addresses are 32-bit words
+// printed in host byte order.
+func parseRoute4(fields []string) (Route, bool) {
+	if len(fields) < 8 || fields[0] == "Iface" {
+		return Route{}, false
+	}
+	flags, err := strconv.ParseUint(fields[3], 16, 32)
+	if err != nil || flags&rtfUp == 0 || flags&rtfReject != 0 {
+		return Route{}, false
+	}
+
+	dst, ok1 := hexIPv4(fields[1])
+	gateway, ok2 := hexIPv4(fields[2])
+	mask, ok3 := hexIPv4(fields[7])
+	if !ok1 || !ok2 || !ok3 {
+		return Route{}, false
+	}
+	metric, _ := strconv.ParseUint(fields[6], 10, 32)
+
+	return Route{
+		Iface:   fields[0],
+		Dst:     &net.IPNet{IP: dst, Mask: net.IPMask(mask)},
+		Gateway: gateway,
+		Metric:  uint32(metric),
+	}, true
+}
+
+// parseRoute6 reads a /proc/net/ipv6_route line: destination, prefix
+// length, source, source prefix length, next hop, metric, refcount, use,
+// flags and device. Addresses are in network byte order.
+func parseRoute6(fields []string) (Route, bool) {
+	if len(fields) < 10 {
+		return Route{}, false
+	}
+	flags, err := strconv.ParseUint(fields[8], 16, 32)
+	if err != nil || flags&rtfUp == 0 || flags&rtfReject != 0 {
+		return Route{}, false
+	}
+
+	dst, err1 := hex.DecodeString(fields[0])
+	prefix, err2 := strconv.ParseUint(fields[1], 16, 8)
+	gateway, err3 := hex.DecodeString(fields[4])
+	metric, err4 := strconv.ParseUint(fields[5], 16, 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || len(dst) != 16 || len(gateway) != 16 {
+		return Route{}, false
+	}
+
+	iface := fields[9]
+	if flags&rtfLocal != 0 {
+		// Addresses of this host: listed on their device, delivered over lo.
+		iface = "lo"
+	}
+	return Route{
+		Iface:   iface,
+		Dst:     &net.IPNet{IP: net.IP(dst), Mask: net.CIDRMask(int(prefix), 128)},
+		Gateway: net.IP(gateway),
+		Metric:  uint32(metric),
+	}, true
+}
+
+func hexIPv4(s string) (net.IP, bool) {
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != 4 {
+		return nil, false
+	}
+	ip := make(net.IP, 4)
+	binary.NativeEndian.PutUint32(ip, binary.BigEndian.Uint32(raw))
+	return ip, true
+}
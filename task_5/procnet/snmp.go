@@ -0,0 +1,125 @@
+package procnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+var snmp6Prefixes = []string{"UdpLite6", "Icmp6", "Udp6", "Ip6"}
+
+func ReadCounters(procRoot string) ([]models.KernelCounter, error) {
+	var counters []models.KernelCounter
+	var errors []string
+
+	for _, name := range []string{"snmp", "netstat"} {
+		found, err := readFile(filepath.Join(procRoot, "net", name), parseHeaderPairs)
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		counters = append(counters, found...)
+	}
+
+	found, err := readFile(filepath.Join(procRoot, "net", "snmp6"), parseSnmp6)
+	if err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		counters = append(counters, found...)
+	}
+
+	if len(counters) == 0 && len(errors) > 0 {
+		return nil, fmt.Errorf("counters: %s", strings.Join(errors, "; "))
+	}
+	return counters, nil
+}
+
+func readFile(filename string, parse func(io.Reader) ([]models.KernelCounter, error)) ([]models.KernelCounter, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parse(file)
+}
+
+// parseHeaderPairs handles /proc/net/snmp and /proc/net/netstat, where each
+// group is a line of counter names followed by a line of values:
+//
+//	TcpExt: SyncookiesSent SyncookiesRecv ...
+//	TcpExt: 0 0 ...
+func parseHeaderPairs(reader io.Reader) ([]models.KernelCounter, error) {
+	var counters []models.KernelCounter
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var header []string
+	var headerGroup string
+	for scanner.Scan() {
+		group, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+
+		if header == nil || group != headerGroup {
+			header = fields
+			headerGroup = group
+			continue
+		}
+
+		for i, field := range fields {
+			if i >= len(header) {
+				break
+			}
+			value, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				continue
+			}
+			counters = append(counters, models.KernelCounter{Name: group + "." + header[i], Value: value})
+		}
+		header = nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading: %v", err)
+	}
+	return counters, nil
+}
+
+func parseSnmp6(reader io.Reader) ([]models.KernelCounter, error) {
+	var counters []models.KernelCounter
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		name := fields[0]
+		for _, prefix := range snmp6Prefixes {
+			if strings.HasPrefix(name, prefix) {
+				name = prefix + "." + strings.TrimPrefix(name, prefix)
+				break
+			}
+		}
+		counters = append(counters, models.KernelCounter{Name: name, Value: value})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading: %v", err)
+	}
+	return counters, nil
+}
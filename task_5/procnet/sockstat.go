@@ -0,0 +1,71 @@
+package procnet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+func ReadSockstat(procRoot string) ([]models.SockstatEntry, error) {
+	var entries []models.SockstatEntry
+	var errors []string
+
+	for _, name := range []string{"sockstat", "sockstat6"} {
+		found, err := readSockstatFile(filepath.Join(procRoot, "net", name))
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		entries = append(entries, found...)
+	}
+
+	if len(entries) == 0 && len(errors) > 0 {
+		return nil, fmt.Errorf("sockstat: %s", strings.Join(errors, "; "))
+	}
+	return entries, nil
+}
+
+func readSockstatFile(filename string) ([]models.SockstatEntry, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseSockstat(file)
+}
+
+// parseSockstat reads lines of the form "TCP: inuse 4 orphan 0 tw 0 alloc 4 mem 0".
+func parseSockstat(reader io.Reader) ([]models.SockstatEntry, error) {
+	var entries []models.SockstatEntry
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		proto, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		entry := models.SockstatEntry{Proto: strings.TrimSpace(proto)}
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseInt(fields[i+1], 10, 64)
+			if err != nil {
+				continue
+			}
+			entry.Counters = append(entry.Counters, models.Counter{Name: fields[i], Value: value})
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading: %v", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,219 @@
+package rates
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+const DefaultHistory = 30
+
+type sample struct {
+	at       time.Time
+	sent     uint64
+	received uint64
+	tx       float64
+	rx       float64
+}
+
+type Tracker struct {
+	mu        sync.Mutex
+	size      int
+	last      map[string]sample
+	history   map[string][]float64
+	processes map[string][]float64
+	lastAt    time.Time
+
+	ifaces       map[string]sample
+	ifaceHistory map[string][]float64
+}
+
+func NewTracker(size int) *Tracker {
+	if size <= 0 {
+		size = DefaultHistory
+	}
+	return &Tracker{
+		size:      size,
+		last:      make(map[string]sample),
+		history:   make(map[string][]float64),
+		processes: make(map[string][]float64),
+
+		ifaces:       make(map[string]sample),
+		ifaceHistory: make(map[string][]float64),
+	}
+}
+
+// Observe fills TxRate, RxRate and RateHistory on every connection that
+// carries TCP byte counters and returns per-process totals. Connections
+// that are gone since the previous call are forgotten, so a reused tuple
+// starts from a fresh baseline instead of producing a bogus spike.
+func (t *Tracker) Observe(conns []models.ConnectionItem, at time.Time) []models.ProcessRate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]bool, len(conns))
+	byProcess := make(map[string]*models.ProcessRate)
+
+	for i := range conns {
+		conn := &conns[i]
+		key := conn.Key()
+		seen[key] = true
+
+		if conn.TCP != nil {
+			current := sample{at: at, sent: conn.TCP.BytesAcked, received: conn.TCP.BytesReceived}
+			prev, ok := t.last[key]
+			switch {
+			case ok && !at.After(prev.at):
+				// Same snapshot seen twice (e.g. a remote agent that has
+				// not ticked yet): keep the previous rates.
+				conn.TxRate, conn.RxRate = prev.tx, prev.rx
+			case ok:
+				elapsed := current.at.Sub(prev.at).Seconds()
+				current.tx = counterDelta(prev.sent, current.sent) / elapsed
+				current.rx = counterDelta(prev.received, current.received) / elapsed
+				conn.TxRate, conn.RxRate = current.tx, current.rx
+				t.last[key] = current
+				t.history[key] = t.push(t.history[key], conn.TxRate+conn.RxRate)
+			default:
+				t.last[key] = current
+			}
+			conn.RateHistory = append([]float64(nil), t.history[key]...)
+		}
+
+		if conn.PID == "" || conn.PID == "N/A" {
+			continue
+		}
+		pkey := conn.Host + "/" + conn.PID
+		proc, ok := byProcess[pkey]
+		if !ok {
+			proc = &models.ProcessRate{Host: conn.Host, PID: conn.PID, Process: conn.Process}
+			byProcess[pkey] = proc
+		}
+		proc.Connections++
+		proc.TxRate += conn.TxRate
+		proc.RxRate += conn.RxRate
+	}
+
+	for key := range t.last {
+		if !seen[key] {
+			delete(t.last, key)
+			delete(t.history, key)
+		}
+	}
+
+	fresh := at.After(t.lastAt)
+	if fresh {
+		t.lastAt = at
+	}
+
+	processes := make([]models.ProcessRate, 0, len(byProcess))
+	for pkey, proc := range byProcess {
+		if fresh {
+			t.processes[pkey] = t.push(t.processes[pkey], proc.TxRate+proc.RxRate)
+		}
+		proc.History = append([]float64(nil), t.processes[pkey]...)
+		processes = append(processes, *proc)
+	}
+	for pkey := range t.processes {
+		if _, ok := byProcess[pkey]; !ok {
+			delete(t.processes, pkey)
+		}
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		ri := processes[i].TxRate + processes[i].RxRate
+		rj := processes[j].TxRate + processes[j].RxRate
+		if ri != rj {
+			return ri > rj
+		}
+		return processes[i].Connections > processes[j].Connections
+	})
+
+	return processes
+}
+
+// ObserveInterfaces fills RxRate, TxRate and RateHistory from the change
+// in each interface's byte counters since the previous call.
+func (t *Tracker) ObserveInterfaces(ifaces []models.Interface, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]bool, len(ifaces))
+	for i := range ifaces {
+		iface := &ifaces[i]
+		seen[iface.Name] = true
+
+		current := sample{at: at, sent: iface.TxBytes, received: iface.RxBytes}
+		prev, ok := t.ifaces[iface.Name]
+		switch {
+		case ok && !at.After(prev.at):
+			iface.TxRate, iface.RxRate = prev.tx, prev.rx
+		case ok:
+			elapsed := current.at.Sub(prev.at).Seconds()
+			current.tx = counterDelta(prev.sent, current.sent) / elapsed
+			current.rx = counterDelta(prev.received, current.received) / elapsed
+			iface.TxRate, iface.RxRate = current.tx, current.rx
+			t.ifaces[iface.Name] = current
+			t.ifaceHistory[iface.Name] = t.push(t.ifaceHistory[iface.Name], iface.TxRate+iface.RxRate)
+		default:
+			t.ifaces[iface.Name] = current
+		}
+		iface.RateHistory = append([]float64(nil), t.ifaceHistory[iface.Name]...)
+	}
+
+	for name := range t.ifaces {
+		if !seen[name] {
+			delete(t.ifaces, name)
+			delete(t.ifaceHistory, name)
+		}
+	}
+}
+
+func (t *Tracker) push(history []float64, value float64) []float64 {
+	history = append(history, value)
+	if len(history) > t.size {
+		history = history[len(history)-t.size:]
+	}
+	return history
+}
+
+// counterDelta treats a counter that went backwards as reset to zero
+// in between samples, so the whole current value counts as new traffic.
+func counterDelta(prev, current uint64) float64 {
+	if current < prev {
+		return float64(current)
+	}
+	return float64(current - prev)
+}
+
+type Collector struct {
+	inner   models.Collector
+	tracker *Tracker
+}
+
+func NewCollector(inner models.Collector) *Collector {
+	return &Collector{inner: inner, tracker: NewTracker(DefaultHistory)}
+}
+
+func (c *Collector) Collect() (*models.Snapshot, error) {
+	snapshot, err := c.inner.Collect()
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Processes = c.tracker.Observe(snapshot.Connections, snapshot.Time)
+	c.tracker.ObserveInterfaces(snapshot.Interfaces, snapshot.Time)
+	return snapshot, nil
+}
+
+func (c *Collector) Hostname() string {
+	return c.inner.Hostname()
+}
+
+func (c *Collector) Health() []models.HostHealth {
+	if reporter, ok := c.inner.(models.HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options are read from the config file with the durations written as
+// Go duration strings, e.g. "timeout": "2s", "ttl": "10m".
+type Options struct {
+	Enabled     bool          `json:"enabled"`
+	Workers     int           `json:"workers"`
+	Timeout     time.Duration `json:"-"`
+	TTL         time.Duration `json:"-"`
+	NegativeTTL time.Duration `json:"-"`
+	// Server sends queries to this host:port instead of the system resolver.
+	Server string `json:"server"`
+}
+
+type optionsJSON struct {
+	*plainOptions
+	Timeout     string `json:"timeout,omitempty"`
+	TTL         string `json:"ttl,omitempty"`
+	NegativeTTL string `json:"negative_ttl,omitempty"`
+}
+
+type plainOptions Options
+
+func (o Options) MarshalJSON() ([]byte, error) {
+	aux := optionsJSON{plainOptions: (*plainOptions)(&o)}
+	if o.Timeout > 0 {
+		aux.Timeout = o.Timeout.String()
+	}
+	if o.TTL > 0 {
+		aux.TTL = o.TTL.String()
+	}
+	if o.NegativeTTL > 0 {
+		aux.NegativeTTL = o.NegativeTTL.String()
+	}
+	return json.Marshal(aux)
+}
+
+func (o *Options) UnmarshalJSON(data []byte) error {
+	aux := optionsJSON{plainOptions: (*plainOptions)(o)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"timeout", aux.Timeout, &o.Timeout},
+		{"ttl", aux.TTL, &o.TTL},
+		{"negative_ttl", aux.NegativeTTL, &o.NegativeTTL},
+	} {
+		if field.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field.value)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %v", field.name, err)
+		}
+		*field.dst = d
+	}
+	return nil
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Workers:     4,
+		Timeout:     2 * time.Second,
+		TTL:         10 * time.Minute,
+		NegativeTTL: time.Minute,
+	}
+}
+
+type Result struct {
+	IP   string
+	Name string
+}
+
+type entry struct {
+	name    string
+	expires time.Time
+	pending bool
+}
+
+// Resolver looks up PTR names on a fixed pool of workers. Lookup never
+// blocks: it answers from the cache and queues a query for anything
+// unknown or expired, with the answer arriving later on Results.
+type Resolver struct {
+	opts     Options
+	resolver *net.Resolver
+	jobs     chan string
+	results  chan Result
+
+	mu        sync.Mutex
+	cache     map[string]entry
+	lastPrune time.Time
+}
+
+func New(opts Options) *Resolver {
+	defaults := DefaultOptions()
+	if opts.Workers <= 0 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaults.TTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = defaults.NegativeTTL
+	}
+
+	resolver := net.DefaultResolver
+	if opts.Server != "" {
+		server := opts.Server
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	r := &Resolver{
+		opts:     opts,
+		resolver: resolver,
+		jobs:     make(chan string, 256),
+		results:  make(chan Result, 256),
+		cache:    make(map[string]entry),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		go r.worker()
+	}
+	return r
+}
+
+func (r *Resolver) Results() <-chan Result {
+	return r.results
+}
+
+// Lookup returns the cached name for ip, or false if none is known yet.
+func (r *Resolver) Lookup(ip string) (string, bool) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastPrune) >= r.opts.NegativeTTL {
+		r.prune(now)
+	}
+
+	e, ok := r.cache[ip]
+	if ok && (e.pending || now.Before(e.expires)) {
+		return e.name, e.name != ""
+	}
+
+	select {
+	case r.jobs <- ip:
+		e.pending = true
+		r.cache[ip] = e
+	default:
+		// Queue full; the next Lookup will try again.
+	}
+	return e.name, e.name != ""
+}
+
+// prune drops answers that expired a full TTL ago. Addresses still in use
+// are refreshed by Lookup long before that, so only ones that are no longer
+// seen go. Called with r.mu held.
+func (r *Resolver) prune(now time.Time) {
+	for ip, e := range r.cache {
+		if !e.pending && now.After(e.expires.Add(r.opts.TTL)) {
+			delete(r.cache, ip)
+		}
+	}
+	r.lastPrune = now
+}
+
+func (r *Resolver) worker() {
+	for ip := range r.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), r.opts.Timeout)
+		names, err := r.resolver.LookupAddr(ctx, ip)
+		cancel()
+
+		e := entry{expires: time.Now().Add(r.opts.NegativeTTL)}
+		if err == nil && len(names) > 0 {
+			e = entry{
+				name:    strings.TrimSuffix(names[0], "."),
+				expires: time.Now().Add(r.opts.TTL),
+			}
+		}
+
+		r.mu.Lock()
+		r.cache[ip] = e
+		r.mu.Unlock()
+
+		if e.name != "" {
+			r.results <- Result{IP: ip, Name: e.name}
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package resolve
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubServer answers PTR queries over UDP from a fixed table. Names mapped
+// to "" get NXDOMAIN; addresses not in the table are never answered.
+type stubServer struct {
+	conn    net.PacketConn
+	answers map[string]string
+
+	mu      sync.Mutex
+	queries map[string]int
+}
+
+func newStubServer(t *testing.T, answers map[string]string) *stubServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubServer{conn: conn, answers: make(map[string]string), queries: make(map[string]int)}
+	for ip, name := range answers {
+		arpa, err := reverseName(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.answers[arpa] = name
+	}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func reverseName(ip string) (string, error) {
+	addr := net.ParseIP(ip).To4()
+	if addr == nil {
+		return "", &net.AddrError{Err: "not an IPv4 address", Addr: ip}
+	}
+	return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", addr[3], addr[2], addr[1], addr[0]), nil
+}
+
+func (s *stubServer) count(ip string) int {
+	arpa, _ := reverseName(ip)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[arpa]
+}
+
+func (s *stubServer) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if reply := s.reply(buf[:n]); reply != nil {
+			s.conn.WriteTo(reply, from)
+		}
+	}
+}
+
+// reply builds the response to a single-question query.
+func (s *stubServer) reply(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	var labels []string
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		size := int(query[end])
+		if end+1+size > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[end+1:end+1+size]))
+		end += 1 + size
+	}
+	end += 5 // root label, QTYPE, QCLASS
+	if end > len(query) {
+		return nil
+	}
+	qname := strings.ToLower(strings.Join(labels, "."))
+
+	s.mu.Lock()
+	s.queries[qname]++
+	s.mu.Unlock()
+
+	name, known := s.answers[qname]
+	if !known {
+		return nil
+	}
+
+	reply := append([]byte(nil), query[:end]...)
+	binary.BigEndian.PutUint16(reply[6:], 0)  // ANCOUNT
+	binary.BigEndian.PutUint16(reply[8:], 0)  // NSCOUNT
+	binary.BigEndian.PutUint16(reply[10:], 0) // ARCOUNT
+	if name == "" {
+		binary.BigEndian.PutUint16(reply[2:], 0x8183) // response, RD, RA, NXDOMAIN
+		return reply
+	}
+	binary.BigEndian.PutUint16(reply[2:], 0x8180)
+	binary.BigEndian.PutUint16(reply[6:], 1)
+
+	var rdata []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		rdata = append(rdata, byte(len(label)))
+		rdata = append(rdata, label...)
+	}
+	rdata = append(rdata, 0)
+
+	reply = append(reply, 0xc0, 12)                   // pointer to the question name
+	reply = binary.BigEndian.AppendUint16(reply, 12)  // PTR
+	reply = binary.BigEndian.AppendUint16(reply, 1)   // IN
+	reply = binary.BigEndian.AppendUint32(reply, 300) // TTL
+	reply = binary.BigEndian.AppendUint16(reply, uint16(len(rdata)))
+	return append(reply, rdata...)
+}
+
+func newTestResolver(server *stubServer, opts Options) *Resolver {
+	opts.Server = server.conn.LocalAddr().String()
+	opts.Workers = 1
+	return New(opts)
+}
+
+// settled waits until no query for ip is in flight.
+func settled(t *testing.T, r *Resolver, ip string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		e, ok := r.cache[ip]
+		r.mu.Unlock()
+		if ok && !e.pending {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("lookup of %s never finished", ip)
+}
+
+func TestLookupCachesForTTL(t *testing.T) {
+	server := newStubServer(t, map[string]string{"192.0.2.1": "host.example."})
+	r := newTestResolver(server, Options{TTL: 200 * time.Millisecond})
+
+	if _, ok := r.Lookup("192.0.2.1"); ok {
+		t.Fatal("first Lookup answered before any query")
+	}
+
+	select {
+	case result := <-r.Results():
+		if result.IP != "192.0.2.1" || result.Name != "host.example" {
+			t.Fatalf("result = %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result")
+	}
+
+	for i := 0; i < 3; i++ {
+		if name, ok := r.Lookup("192.0.2.1"); !ok || name != "host.example" {
+			t.Fatalf("Lookup = %q, %v", name, ok)
+		}
+	}
+	if n := server.count("192.0.2.1"); n != 1 {
+		t.Fatalf("%d queries within the TTL, want 1", n)
+	}
+
+	// Once expired the stale name is still returned while it is refreshed.
+	time.Sleep(250 * time.Millisecond)
+	if name, ok := r.Lookup("192.0.2.1"); !ok || name != "host.example" {
+		t.Fatalf("Lookup after expiry = %q, %v", name, ok)
+	}
+	<-r.Results()
+	if n := server.count("192.0.2.1"); n != 2 {
+		t.Fatalf("%d queries after the TTL, want 2", n)
+	}
+}
+
+func TestLookupNegativeCache(t *testing.T) {
+	server := newStubServer(t, map[string]string{"192.0.2.2": ""})
+	r := newTestResolver(server, Options{NegativeTTL: 200 * time.Millisecond})
+
+	r.Lookup("192.0.2.2")
+	settled(t, r, "192.0.2.2")
+
+	for i := 0; i < 3; i++ {
+		if name, ok := r.Lookup("192.0.2.2"); ok {
+			t.Fatalf("Lookup = %q for an NXDOMAIN answer", name)
+		}
+	}
+	if n := server.count("192.0.2.2"); n != 1 {
+		t.Fatalf("%d queries within the negative TTL, want 1", n)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	r.Lookup("192.0.2.2")
+	settled(t, r, "192.0.2.2")
+	if n := server.count("192.0.2.2"); n != 2 {
+		t.Fatalf("%d queries after the negative TTL, want 2", n)
+	}
+}
+
+func TestLookupTimeout(t *testing.T) {
+	server := newStubServer(t, nil)
+	r := newTestResolver(server, Options{Timeout: 100 * time.Millisecond, NegativeTTL: time.Minute})
+
+	start := time.Now()
+	r.Lookup("192.0.2.3")
+	settled(t, r, "192.0.2.3")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("lookup took %v with a 100ms timeout", elapsed)
+	}
+	if server.count("192.0.2.3") == 0 {
+		t.Fatal("server never saw the query")
+	}
+
+	// A timeout is cached like a negative answer.
+	before := server.count("192.0.2.3")
+	if _, ok := r.Lookup("192.0.2.3"); ok {
+		t.Fatal("Lookup answered after a timeout")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := server.count("192.0.2.3"); n != before {
+		t.Fatalf("timed-out address queried again (%d, was %d)", n, before)
+	}
+}
+
+func TestPruneDropsExpiredEntries(t *testing.T) {
+	r := &Resolver{
+		opts:  Options{TTL: time.Minute, NegativeTTL: time.Minute},
+		cache: make(map[string]entry),
+	}
+	now := time.Now()
+	r.cache["192.0.2.10"] = entry{name: "fresh", expires: now.Add(time.Minute)}
+	r.cache["192.0.2.11"] = entry{name: "stale", expires: now.Add(-time.Second)}
+	r.cache["192.0.2.12"] = entry{name: "gone", expires: now.Add(-2 * time.Minute)}
+	r.cache["192.0.2.13"] = entry{expires: now.Add(-2 * time.Minute)}
+	r.cache["192.0.2.14"] = entry{pending: true}
+
+	r.prune(now)
+
+	for _, ip := range []string{"192.0.2.10", "192.0.2.11", "192.0.2.14"} {
+		if _, ok := r.cache[ip]; !ok {
+			t.Errorf("%s pruned", ip)
+		}
+	}
+	for _, ip := range []string{"192.0.2.12", "192.0.2.13"} {
+		if _, ok := r.cache[ip]; ok {
+			t.Errorf("%s kept", ip)
+		}
+	}
+}
+
+func TestOptionsJSON(t *testing.T) {
+	var opts Options
+	data := `{"enabled": true, "workers": 2, "timeout": "500ms", "ttl": "1h", "negative_ttl": "30s", "server": "127.0.0.1:53"}`
+	if err := json.Unmarshal([]byte(data), &opts); err != nil {
+		t.Fatal(err)
+	}
+	want := Options{Enabled: true, Workers: 2, Timeout: 500 * time.Millisecond, TTL: time.Hour, NegativeTTL: 30 * time.Second, Server: "127.0.0.1:53"}
+	if opts != want {
+		t.Fatalf("got %+v, want %+v", opts, want)
+	}
+
+	out, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Options
+	if err := json.Unmarshal(out, &back); err != nil || back != want {
+		t.Fatalf("round trip %s = %+v, %v", out, back, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"ttl": "soon"}`), &opts); err == nil {
+		t.Fatal("accepted an invalid duration")
+	}
+}
@@ -0,0 +1,50 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(ruleSet []Rule) *Engine {
+	var enabled []Rule
+	for _, rule := range ruleSet {
+		if !rule.Disabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	return &Engine{rules: enabled}
+}
+
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+func (e *Engine) Observe(snapshot *models.Snapshot) []models.Alert {
+	listening := connections.Listeners(snapshot.Connections)
+
+	var alerts []models.Alert
+	for _, conn := range snapshot.Connections {
+		for _, rule := range e.rules {
+			if !rule.Match.matches(conn, listening) {
+				continue
+			}
+			flagged := conn
+			alerts = append(alerts, models.Alert{
+				Time:     snapshot.Time,
+				Severity: rule.Severity,
+				Source:   rule.ID,
+				Key:      "rule|" + rule.ID + "|" + conn.Key(),
+				Message: fmt.Sprintf("%s: %s %s → %s (%s, PID %s)",
+					rule.Description, conn.Proto, conn.Local, conn.Remote, conn.Process, conn.PID),
+				Connection: &flagged,
+			})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,224 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// Rule flags every connection that satisfies all of the conditions set in
+// Match. Conditions left empty are not checked.
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	Match       Match  `json:"match"`
+}
+
+type Match struct {
+	Processes         []string `json:"processes,omitempty"`
+	Protos            []string `json:"protos,omitempty"`
+	States            []string `json:"states,omitempty"`
+	LocalIPs          []string `json:"local_ips,omitempty"`
+	RemoteScopes      []string `json:"remote_scopes,omitempty"`
+	RemotePortAbove   int      `json:"remote_port_above,omitempty"`
+	ExceptRemotePorts []int    `json:"except_remote_ports,omitempty"`
+	Outbound          bool     `json:"outbound,omitempty"`
+	NonRoot           bool     `json:"non_root,omitempty"`
+	ExeDeleted        bool     `json:"exe_deleted,omitempty"`
+	ExeReplaced       bool     `json:"exe_replaced,omitempty"`
+	ExeUnsafeDir      bool     `json:"exe_unsafe_dir,omitempty"`
+	ExePaths          []string `json:"exe_paths,omitempty"`
+	ExeSHA256         []string `json:"exe_sha256,omitempty"`
+}
+
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+func Default() []Rule {
+	return []Rule{
+		{
+			ID:          "shell-socket",
+			Description: "shell or netcat holds a network socket",
+			Severity:    models.SeverityCritical,
+			Match: Match{
+				Processes: []string{"bash", "sh", "dash", "zsh", "ksh", "nc", "ncat", "netcat", "socat"},
+			},
+		},
+		{
+			ID:          "wildcard-listener",
+			Description: "non-root listener bound to all interfaces",
+			Severity:    models.SeverityWarning,
+			Match: Match{
+				States:   []string{"LISTEN"},
+				LocalIPs: []string{"0.0.0.0", "::"},
+				NonRoot:  true,
+			},
+		},
+		{
+			ID:          "uncommon-port",
+			Description: "outbound connection to an uncommon high port",
+			Severity:    models.SeverityInfo,
+			Match: Match{
+				States:            []string{"ESTABLISHED", "SYN_SENT"},
+				RemoteScopes:      []string{"public"},
+				RemotePortAbove:   1023,
+				ExceptRemotePorts: []int{1194, 1883, 3306, 3478, 5222, 5228, 5432, 6379, 8000, 8080, 8443, 8883, 9000, 9418, 27017},
+				Outbound:          true,
+			},
+		},
+		{
+			ID:          "deleted-exe",
+			Description: "process runs from a deleted executable",
+			Severity:    models.SeverityCritical,
+			Match: Match{
+				ExeDeleted: true,
+			},
+		},
+		{
+			ID:          "unsafe-exe-dir",
+			Description: "process runs from a world-writable or tmp directory",
+			Severity:    models.SeverityCritical,
+			Match: Match{
+				ExeUnsafeDir: true,
+			},
+		},
+		{
+			ID:          "replaced-exe",
+			Description: "process binary was replaced on disk after it started",
+			Severity:    models.SeverityWarning,
+			Match: Match{
+				ExeReplaced: true,
+			},
+		},
+	}
+}
+
+// Load reads custom rules from path and merges them over the defaults: a
+// rule with the ID of a default replaces it, and "disabled": true turns it
+// off.
+func Load(path string) ([]Rule, error) {
+	ruleSet := Default()
+	if path == "" {
+		return ruleSet, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %v", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules %s: %v", path, err)
+	}
+
+	for _, rule := range file.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rules %s: %v", path, err)
+		}
+		ruleSet = merge(ruleSet, rule)
+	}
+	return ruleSet, nil
+}
+
+func merge(ruleSet []Rule, rule Rule) []Rule {
+	for i := range ruleSet {
+		if ruleSet[i].ID == rule.ID {
+			ruleSet[i] = rule
+			return ruleSet
+		}
+	}
+	return append(ruleSet, rule)
+}
+
+func (r Rule) validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	switch r.Severity {
+	case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+	case "":
+		if !r.Disabled {
+			return fmt.Errorf("rule %q has no severity", r.ID)
+		}
+	default:
+		return fmt.Errorf("rule %q has unknown severity %q", r.ID, r.Severity)
+	}
+	return nil
+}
+
+func (m Match) matches(conn models.ConnectionItem, listening connections.ListenSet) bool {
+	if len(m.Processes) > 0 && !containsFold(m.Processes, conn.Process) {
+		return false
+	}
+	if len(m.Protos) > 0 && !containsFold(m.Protos, conn.Proto) && !containsFold(m.Protos, strings.TrimSuffix(conn.Proto, "6")) {
+		return false
+	}
+	if len(m.States) > 0 && !containsFold(m.States, conn.State) {
+		return false
+	}
+	if len(m.LocalIPs) > 0 && !containsFold(m.LocalIPs, conn.LocalIP) {
+		return false
+	}
+	if len(m.RemoteScopes) > 0 && !containsFold(m.RemoteScopes, connections.AddressScope(conn.RemoteIP)) {
+		return false
+	}
+	if m.RemotePortAbove > 0 && conn.RemotePort <= m.RemotePortAbove {
+		return false
+	}
+	for _, port := range m.ExceptRemotePorts {
+		if conn.RemotePort == port {
+			return false
+		}
+	}
+	if m.Outbound && !listening.IsOutbound(conn) {
+		return false
+	}
+	if m.NonRoot && (conn.UID == "" || conn.IsRootOwned()) {
+		return false
+	}
+	if m.ExeDeleted && !conn.ExeDeleted {
+		return false
+	}
+	if m.ExeReplaced && !conn.ExeReplaced {
+		return false
+	}
+	if m.ExeUnsafeDir && !conn.ExeUnsafeDir {
+		return false
+	}
+	if len(m.ExePaths) > 0 && !hasPathPrefix(m.ExePaths, conn.Exe) {
+		return false
+	}
+	if len(m.ExeSHA256) > 0 && !containsFold(m.ExeSHA256, conn.ExeSHA256) {
+		return false
+	}
+	return true
+}
+
+func hasPathPrefix(prefixes []string, path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mizerael/infsec_ssu/task_5/api"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/detect"
+	"github.com/mizerael/infsec_ssu/task_5/history"
+	"github.com/mizerael/infsec_ssu/task_5/intel"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/resolve"
+)
+
+func runServer(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8765", "TCP address or unix:/path/to/socket to serve the API on")
+	socketMode := fs.String("socket-mode", "0660", "permissions for the unix socket")
+	procRoot := fs.String("proc", "/proc", "procfs mount to read sockets and processes from")
+	interval := fs.Duration("interval", 5*time.Second, "how often to collect a new snapshot")
+	historySize := fs.Int("history", 1000, "number of connection events to keep")
+	blocklists := fs.String("blocklist", "", "comma-separated blocklist files (IP/CIDR lists, FireHOL netsets, STIX .json, .csv) to flag remote addresses against")
+	resolveNames := fs.Bool("resolve", false, "look up reverse DNS names to match domain indicators in the blocklists against")
+	dnsServer := fs.String("dns-server", "", "DNS server (host:port) for reverse lookups instead of the system resolver")
+	fs.Parse(args)
+
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil {
+		fmt.Printf("Error: invalid socket mode %q\n", *socketMode)
+		os.Exit(1)
+	}
+
+	var collector models.Collector = connections.NewCollector(*procRoot)
+	if *blocklists != "" {
+		blocklist, err := intel.Load(strings.Split(*blocklists, ","))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var names intel.Names
+		if *resolveNames {
+			opts := resolve.DefaultOptions()
+			opts.Server = *dnsServer
+			resolver := resolve.New(opts)
+			// Nothing here waits for names; the matcher reads the cache.
+			go func() {
+				for range resolver.Results() {
+				}
+			}()
+			names = resolver
+		}
+
+		collector = detect.NewCollector(collector, intel.NewMatcher(blocklist, names))
+	}
+
+	server := api.NewServer(collector, *interval, history.New(*historySize))
+	go server.Run(context.Background())
+
+	listener, err := api.Listen(*listen, os.FileMode(mode))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Serving API on %s\n", *listen)
+	if err := http.Serve(listener, server.Handler()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
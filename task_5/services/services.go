@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Service struct {
+	Name string
+	// Abused marks ports that are rarely legitimate and show up in
+	// malware, C2 and pentest tooling defaults.
+	Abused bool
+}
+
+// builtin fills the gaps in /etc/services, which is often trimmed in
+// containers and never lists the ports attackers like.
+var builtin = []struct {
+	port   int
+	proto  string
+	name   string
+	abused bool
+}{
+	{22, "tcp", "ssh", false},
+	{53, "tcp", "domain", false},
+	{53, "udp", "domain", false},
+	{67, "udp", "bootps", false},
+	{68, "udp", "bootpc", false},
+	{80, "tcp", "http", false},
+	{123, "udp", "ntp", false},
+	{443, "tcp", "https", false},
+	{443, "udp", "quic", false},
+	{853, "tcp", "domain-s", false},
+	{1080, "tcp", "socks", false},
+	{1194, "udp", "openvpn", false},
+	{1883, "tcp", "mqtt", false},
+	{2375, "tcp", "docker", false},
+	{2376, "tcp", "docker-s", false},
+	{3000, "tcp", "dev-http", false},
+	{3306, "tcp", "mysql", false},
+	{3389, "tcp", "ms-wbt-server", false},
+	{4789, "udp", "vxlan", false},
+	{5060, "udp", "sip", false},
+	{5353, "udp", "mdns", false},
+	{5355, "udp", "llmnr", false},
+	{5432, "tcp", "postgresql", false},
+	{5672, "tcp", "amqp", false},
+	{5900, "tcp", "vnc", false},
+	{6379, "tcp", "redis", false},
+	{6443, "tcp", "kube-apiserver", false},
+	{8080, "tcp", "http-alt", false},
+	{8443, "tcp", "https-alt", false},
+	{9090, "tcp", "prometheus", false},
+	{9100, "tcp", "node-exporter", false},
+	{9200, "tcp", "elasticsearch", false},
+	{10250, "tcp", "kubelet", false},
+	{11211, "tcp", "memcache", false},
+	{27017, "tcp", "mongodb", false},
+	{51820, "udp", "wireguard", false},
+
+	{1337, "tcp", "leet", true},
+	{4444, "tcp", "metasploit", true},
+	{4445, "tcp", "metasploit-alt", true},
+	{5555, "tcp", "adb", true},
+	{6666, "tcp", "irc-bot", true},
+	{6667, "tcp", "ircd", true},
+	{6697, "tcp", "ircs", true},
+	{12345, "tcp", "netbus", true},
+	{31337, "tcp", "back-orifice", true},
+	{31337, "udp", "back-orifice", true},
+	{50050, "tcp", "cobaltstrike", true},
+}
+
+type DB struct {
+	byPort map[string]Service
+}
+
+func key(proto string, port int) string {
+	return Proto(proto) + "/" + strconv.Itoa(port)
+}
+
+// Proto maps a socket protocol such as "TCP6" or "UDPLITE" onto the
+// tcp/udp split services are registered under.
+func Proto(proto string) string {
+	proto = strings.ToLower(proto)
+	switch {
+	case strings.HasPrefix(proto, "tcp"):
+		return "tcp"
+	case strings.HasPrefix(proto, "udp"):
+		return "udp"
+	}
+	return proto
+}
+
+func Builtin() *DB {
+	db := &DB{byPort: make(map[string]Service)}
+	for _, s := range builtin {
+		db.byPort[key(s.proto, s.port)] = Service{Name: s.name, Abused: s.abused}
+	}
+	return db
+}
+
+// Load reads an /etc/services style file over the built-in table. The
+// system names win, but the abused marks are kept.
+func Load(path string) (*DB, error) {
+	db := Builtin()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read services: %v", err)
+	}
+	defer f.Close()
+
+	if err := db.read(f); err != nil {
+		return nil, fmt.Errorf("failed to parse services %s: %v", path, err)
+	}
+	return db, nil
+}
+
+func (db *DB) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		portText, proto, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(portText)
+		if err != nil {
+			continue
+		}
+
+		k := key(proto, port)
+		service := db.byPort[k]
+		service.Name = fields[0]
+		db.byPort[k] = service
+	}
+	return scanner.Err()
+}
+
+func (db *DB) Lookup(proto string, port int) (Service, bool) {
+	s, ok := db.byPort[key(proto, port)]
+	return s, ok
+}
+
+// Abused returns the first of ports that is marked as commonly abused.
+func (db *DB) Abused(proto string, ports ...int) (int, string, bool) {
+	for _, port := range ports {
+		if s, ok := db.Lookup(proto, port); ok && s.Abused && port != 0 {
+			return port, s.Name, true
+		}
+	}
+	return 0, "", false
+}
+
+var (
+	defaultDB   *DB
+	defaultOnce sync.Once
+)
+
+// Default is the table from /etc/services, or the built-in one when the
+// file is missing.
+func Default() *DB {
+	defaultOnce.Do(func() {
+		db, err := Load("/etc/services")
+		if err != nil {
+			db = Builtin()
+		}
+		defaultDB = db
+	})
+	return defaultDB
+}
@@ -0,0 +1,151 @@
+package theme
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Styles struct {
+	Theme      Theme
+	Title      lipgloss.Style
+	Status     lipgloss.Style
+	Error      lipgloss.Style
+	Help       lipgloss.Style
+	HelpKey    lipgloss.Style
+	ListTitle  lipgloss.Style
+	ListStyles list.Styles
+	ItemStyles list.DefaultItemStyles
+	Badge      lipgloss.Style
+	Warning    lipgloss.Style
+	Accent     lipgloss.Style
+}
+
+func (t Theme) color(c string) lipgloss.TerminalColor {
+	if t.Monochrome || c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+func (t Theme) Styles() Styles {
+	s := Styles{Theme: t}
+
+	s.Title = lipgloss.NewStyle().
+		Foreground(t.color(t.TitleFg)).
+		Background(t.color(t.TitleBg)).
+		Padding(0, 1).
+		Bold(true)
+
+	s.Status = lipgloss.NewStyle().
+		Foreground(t.color(t.Status)).
+		Italic(true).
+		Padding(0, 1)
+
+	s.Error = lipgloss.NewStyle().
+		Foreground(t.color(t.Error)).
+		Bold(true)
+
+	s.Help = lipgloss.NewStyle().
+		Foreground(t.color(t.Help)).
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.color(t.HelpBorder)).
+		Padding(0, 1)
+
+	s.HelpKey = lipgloss.NewStyle().
+		Foreground(t.color(t.HelpKey)).
+		Bold(true)
+
+	s.ListTitle = lipgloss.NewStyle().
+		Foreground(t.color(t.TitleFg)).
+		Background(t.color(t.TitleBg)).
+		Padding(0, 1)
+
+	s.ListStyles = list.DefaultStyles()
+	s.ListStyles.Title = s.ListTitle
+	s.ListStyles.StatusBar = s.ListStyles.StatusBar.Foreground(t.color(t.Status))
+	s.ListStyles.StatusBarFilterCount = s.ListStyles.StatusBarFilterCount.Foreground(t.color(t.Dimmed))
+	s.ListStyles.StatusEmpty = s.ListStyles.StatusEmpty.Foreground(t.color(t.Dimmed))
+	s.ListStyles.NoItems = s.ListStyles.NoItems.Foreground(t.color(t.Dimmed))
+	s.ListStyles.FilterPrompt = s.ListStyles.FilterPrompt.Foreground(t.color(t.Accent))
+	s.ListStyles.FilterCursor = s.ListStyles.FilterCursor.Foreground(t.color(t.FilterMatch))
+	s.ListStyles.ActivePaginationDot = s.ListStyles.ActivePaginationDot.Foreground(t.color(t.NormalTitle))
+	s.ListStyles.InactivePaginationDot = s.ListStyles.InactivePaginationDot.Foreground(t.color(t.Dimmed))
+	s.ListStyles.DividerDot = s.ListStyles.DividerDot.Foreground(t.color(t.Dimmed))
+
+	s.ItemStyles = list.NewDefaultItemStyles()
+	s.ItemStyles.NormalTitle = s.ItemStyles.NormalTitle.Foreground(t.color(t.NormalTitle))
+	s.ItemStyles.NormalDesc = s.ItemStyles.NormalDesc.Foreground(t.color(t.NormalDesc))
+	s.ItemStyles.SelectedTitle = s.ItemStyles.SelectedTitle.
+		Foreground(t.color(t.SelectedTitle)).
+		BorderForeground(t.color(t.SelectedTitle))
+	s.ItemStyles.SelectedDesc = s.ItemStyles.SelectedDesc.
+		Foreground(t.color(t.SelectedDesc)).
+		BorderForeground(t.color(t.SelectedTitle))
+	s.ItemStyles.DimmedTitle = s.ItemStyles.DimmedTitle.Foreground(t.color(t.Dimmed))
+	s.ItemStyles.DimmedDesc = s.ItemStyles.DimmedDesc.Foreground(t.color(t.Dimmed))
+	s.ItemStyles.FilterMatch = s.ItemStyles.FilterMatch.Foreground(t.color(t.FilterMatch))
+
+	s.Badge = lipgloss.NewStyle().
+		Foreground(t.color(t.TitleFg)).
+		Background(t.color(t.Warning)).
+		Bold(true)
+
+	s.Warning = lipgloss.NewStyle().
+		Foreground(t.color(t.Warning)).
+		Bold(true)
+
+	s.Accent = lipgloss.NewStyle().
+		Foreground(t.color(t.Accent))
+
+	if t.Monochrome {
+		s.Title = s.Title.Reverse(true)
+		s.ListTitle = s.ListTitle.Reverse(true)
+		s.ListStyles.Title = s.ListTitle
+		s.Error = s.Error.Underline(true)
+		s.ItemStyles.SelectedTitle = s.ItemStyles.SelectedTitle.Bold(true)
+		s.ItemStyles.FilterMatch = s.ItemStyles.FilterMatch.Underline(true)
+		s.Badge = s.Badge.Reverse(true)
+	}
+
+	return s
+}
+
+func (s Styles) State(state string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if s.Theme.Monochrome {
+		switch strings.ToUpper(state) {
+		case "LISTEN", "LISTENING":
+			return style.Bold(true)
+		case "TIME_WAIT", "CLOSE", "FIN_WAIT1", "FIN_WAIT2", "LAST_ACK", "CLOSING":
+			return style.Faint(true)
+		case "CLOSE_WAIT":
+			return style.Underline(true)
+		}
+		return style
+	}
+
+	state = strings.ToUpper(state)
+	if state == "LISTENING" {
+		state = "LISTEN"
+	}
+	if c, ok := s.Theme.States[state]; ok {
+		return style.Foreground(s.Theme.color(c))
+	}
+	return style.Foreground(s.Theme.color(s.Theme.NormalTitle))
+}
+
+func (s Styles) Scope(scope string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if s.Theme.Monochrome {
+		if scope == "public" || scope == "any" {
+			return style.Bold(true)
+		}
+		return style
+	}
+	if c, ok := s.Theme.Scopes[scope]; ok {
+		return style.Foreground(s.Theme.color(c))
+	}
+	return style.Foreground(s.Theme.color(s.Theme.NormalDesc))
+}
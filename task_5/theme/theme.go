@@ -0,0 +1,258 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Theme struct {
+	Name          string `json:"name"`
+	Base          string `json:"base"`
+	TitleFg       string `json:"title_fg"`
+	TitleBg       string `json:"title_bg"`
+	Status        string `json:"status"`
+	Error         string `json:"error"`
+	Help          string `json:"help"`
+	HelpBorder    string `json:"help_border"`
+	HelpKey       string `json:"help_key"`
+	NormalTitle   string `json:"normal_title"`
+	NormalDesc    string `json:"normal_desc"`
+	SelectedTitle string `json:"selected_title"`
+	SelectedDesc  string `json:"selected_desc"`
+	Dimmed        string `json:"dimmed"`
+	FilterMatch   string `json:"filter_match"`
+	Accent        string `json:"accent"`
+	Warning       string `json:"warning"`
+	Monochrome    bool   `json:"monochrome"`
+
+	States map[string]string `json:"states"`
+	Scopes map[string]string `json:"scopes"`
+}
+
+var builtins = map[string]Theme{
+	"dark": {
+		Name:          "dark",
+		TitleFg:       "229",
+		TitleBg:       "62",
+		Status:        "243",
+		Error:         "203",
+		Help:          "241",
+		HelpBorder:    "241",
+		HelpKey:       "255",
+		NormalTitle:   "252",
+		NormalDesc:    "245",
+		SelectedTitle: "229",
+		SelectedDesc:  "201",
+		Dimmed:        "240",
+		FilterMatch:   "212",
+		Accent:        "62",
+		Warning:       "214",
+		States: map[string]string{
+			"LISTEN":      "42",
+			"ESTABLISHED": "39",
+			"SYN_SENT":    "178",
+			"SYN_RECV":    "178",
+			"FIN_WAIT1":   "244",
+			"FIN_WAIT2":   "244",
+			"TIME_WAIT":   "240",
+			"CLOSE_WAIT":  "208",
+			"LAST_ACK":    "244",
+			"CLOSING":     "244",
+			"CLOSE":       "240",
+		},
+		Scopes: map[string]string{
+			"loopback":   "244",
+			"private":    "79",
+			"public":     "203",
+			"multicast":  "141",
+			"link-local": "109",
+			"any":        "214",
+		},
+	},
+	"light": {
+		Name:          "light",
+		TitleFg:       "231",
+		TitleBg:       "25",
+		Status:        "238",
+		Error:         "160",
+		Help:          "240",
+		HelpBorder:    "245",
+		HelpKey:       "232",
+		NormalTitle:   "235",
+		NormalDesc:    "240",
+		SelectedTitle: "25",
+		SelectedDesc:  "127",
+		Dimmed:        "248",
+		FilterMatch:   "161",
+		Accent:        "25",
+		Warning:       "166",
+		States: map[string]string{
+			"LISTEN":      "28",
+			"ESTABLISHED": "26",
+			"SYN_SENT":    "136",
+			"SYN_RECV":    "136",
+			"FIN_WAIT1":   "242",
+			"FIN_WAIT2":   "242",
+			"TIME_WAIT":   "246",
+			"CLOSE_WAIT":  "166",
+			"LAST_ACK":    "242",
+			"CLOSING":     "242",
+			"CLOSE":       "246",
+		},
+		Scopes: map[string]string{
+			"loopback":   "242",
+			"private":    "30",
+			"public":     "160",
+			"multicast":  "91",
+			"link-local": "31",
+			"any":        "166",
+		},
+	},
+	"high-contrast": {
+		Name:          "high-contrast",
+		TitleFg:       "0",
+		TitleBg:       "11",
+		Status:        "15",
+		Error:         "9",
+		Help:          "15",
+		HelpBorder:    "15",
+		HelpKey:       "11",
+		NormalTitle:   "15",
+		NormalDesc:    "7",
+		SelectedTitle: "11",
+		SelectedDesc:  "14",
+		Dimmed:        "8",
+		FilterMatch:   "10",
+		Accent:        "11",
+		Warning:       "11",
+		States: map[string]string{
+			"LISTEN":      "10",
+			"ESTABLISHED": "14",
+			"SYN_SENT":    "11",
+			"SYN_RECV":    "11",
+			"FIN_WAIT1":   "7",
+			"FIN_WAIT2":   "7",
+			"TIME_WAIT":   "8",
+			"CLOSE_WAIT":  "9",
+			"LAST_ACK":    "7",
+			"CLOSING":     "7",
+			"CLOSE":       "8",
+		},
+		Scopes: map[string]string{
+			"loopback":   "7",
+			"private":    "10",
+			"public":     "9",
+			"multicast":  "13",
+			"link-local": "14",
+			"any":        "11",
+		},
+	},
+	"mono": {
+		Name:       "mono",
+		Monochrome: true,
+	},
+}
+
+func Builtin(name string) (Theme, bool) {
+	t, ok := builtins[strings.ToLower(name)]
+	return t, ok
+}
+
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+func Detect() Theme {
+	if NoColor() {
+		return builtins["mono"]
+	}
+	if lipgloss.HasDarkBackground() {
+		return builtins["dark"]
+	}
+	return builtins["light"]
+}
+
+// Resolve picks the named theme. NO_COLOR only applies when no theme was
+// chosen explicitly, as no-color.org allows.
+func Resolve(name string, custom map[string]Theme) (Theme, error) {
+	if name == "" || name == "auto" {
+		return Detect(), nil
+	}
+
+	if t, ok := custom[name]; ok {
+		t.Name = name
+		return t.inherit(), nil
+	}
+
+	if t, ok := Builtin(name); ok {
+		return t, nil
+	}
+
+	return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// inherit fills colours left empty in a custom theme from the built-in
+// theme it names as its base, so config files only list what they change.
+func (t Theme) inherit() Theme {
+	if t.Monochrome {
+		return t
+	}
+
+	base, ok := Builtin(t.Base)
+	if !ok {
+		base = builtins["dark"]
+	}
+	if base.Monochrome {
+		t.Monochrome = true
+		return t
+	}
+
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+
+	fill(&t.TitleFg, base.TitleFg)
+	fill(&t.TitleBg, base.TitleBg)
+	fill(&t.Status, base.Status)
+	fill(&t.Error, base.Error)
+	fill(&t.Help, base.Help)
+	fill(&t.HelpBorder, base.HelpBorder)
+	fill(&t.HelpKey, base.HelpKey)
+	fill(&t.NormalTitle, base.NormalTitle)
+	fill(&t.NormalDesc, base.NormalDesc)
+	fill(&t.SelectedTitle, base.SelectedTitle)
+	fill(&t.SelectedDesc, base.SelectedDesc)
+	fill(&t.Dimmed, base.Dimmed)
+	fill(&t.FilterMatch, base.FilterMatch)
+	fill(&t.Accent, base.Accent)
+	fill(&t.Warning, base.Warning)
+	t.States = mergeColors(t.States, base.States)
+	t.Scopes = mergeColors(t.Scopes, base.Scopes)
+	return t
+}
+
+func mergeColors(custom, base map[string]string) map[string]string {
+	merged := make(map[string]string, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range custom {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/actions"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// WithActions enables signalling socket owners and destroying sockets.
+// Without it the TUI is read-only.
+func (m Model) WithActions(r models.ActionRunner) Model {
+	m.Actions = r
+	return m
+}
+
+func (m Model) startAction(kind models.ActionKind) (Model, tea.Cmd) {
+	if m.Actions == nil {
+		m.StatusMsg = "Read-only: actions are disabled"
+		return m, nil
+	}
+	if m.ActiveView != models.ViewConnections {
+		return m, nil
+	}
+	conn, ok := m.selectedConnection()
+	if !ok {
+		return m, nil
+	}
+
+	pending := &models.PendingAction{Kind: kind, Connection: conn}
+	switch kind {
+	case models.ActionSignal:
+		pending.PIDs = m.Actions.Owners(conn)
+		if len(pending.PIDs) == 0 {
+			m.StatusMsg = "No visible process owns this socket"
+			return m, nil
+		}
+	case models.ActionDestroy:
+		if !strings.HasPrefix(conn.Proto, "TCP") {
+			m.StatusMsg = "Only TCP sockets can be destroyed"
+			return m, nil
+		}
+	}
+
+	m.Confirm = pending
+	return m, nil
+}
+
+func (m Model) handleConfirmMode(msg tea.KeyMsg) (Model, tea.Cmd) {
+	pending := *m.Confirm
+
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.Confirm = nil
+		m.StatusMsg = "Running action..."
+		return m, m.runActionCmd(pending)
+
+	case "n", "N", "esc", "ctrl+c", "q":
+		m.Confirm = nil
+		m.StatusMsg = "Action cancelled"
+		return m, nil
+
+	case "left", "h", "shift+tab":
+		if pending.Kind == models.ActionSignal {
+			pending.Signal = (pending.Signal + len(actions.Signals) - 1) % len(actions.Signals)
+		}
+
+	case "right", "l", "tab":
+		if pending.Kind == models.ActionSignal {
+			pending.Signal = (pending.Signal + 1) % len(actions.Signals)
+		}
+	}
+
+	m.Confirm = &pending
+	return m, nil
+}
+
+func (m Model) runActionCmd(pending models.PendingAction) tea.Cmd {
+	runner := m.Actions
+	return func() tea.Msg {
+		var status string
+		var err error
+		switch pending.Kind {
+		case models.ActionSignal:
+			status, err = runner.Signal(pending.PIDs, actions.Signals[pending.Signal].Name)
+		case models.ActionDestroy:
+			status, err = runner.Destroy(pending.Connection)
+		}
+
+		done := models.ActionDoneMsg{Status: status}
+		if err != nil {
+			done.Err = err.Error()
+		}
+		return done
+	}
+}
+
+func (m Model) renderConfirm() string {
+	pending := m.Confirm
+	conn := pending.Connection
+	var s strings.Builder
+
+	titleStyle := m.Styles.Title.
+		Width(m.Width - 1).
+		Align(lipgloss.Center)
+
+	title := "Confirm"
+	if m.Actions.DryRun() {
+		title += " (dry run)"
+	}
+	s.WriteString(titleStyle.Render(title))
+	s.WriteString("\n\n")
+
+	s.WriteString(fmt.Sprintf("%s %s → %s  %s\n\n", conn.Proto, conn.Local, conn.Remote, conn.State))
+
+	switch pending.Kind {
+	case models.ActionSignal:
+		s.WriteString("Send signal to the processes holding this socket:\n\n")
+		for _, pid := range pending.PIDs {
+			s.WriteString(fmt.Sprintf("  PID %-8s %s\n", pid, m.processName(pid, conn)))
+		}
+		s.WriteString("\n")
+
+		var choices []string
+		for i, sig := range actions.Signals {
+			if i == pending.Signal {
+				choices = append(choices, m.Styles.Badge.Render(" "+sig.Name+" "))
+			} else {
+				choices = append(choices, " "+sig.Name+" ")
+			}
+		}
+		s.WriteString(strings.Join(choices, " "))
+		s.WriteString("\n\n")
+		s.WriteString("(←/→ choose signal, y/Enter to send, n/Esc to cancel)")
+
+	case models.ActionDestroy:
+		s.WriteString("Destroy this TCP socket? The peer gets a reset and the owning\n")
+		s.WriteString(fmt.Sprintf("process (%s, PID %s) sees the connection aborted.\n\n", conn.Process, conn.PID))
+		s.WriteString("(y/Enter to destroy, n/Esc to cancel)")
+	}
+
+	return s.String()
+}
+
+func (m Model) processName(pid string, conn models.ConnectionItem) string {
+	if pid == conn.PID {
+		return conn.Process
+	}
+	for _, ref := range conn.Stdio {
+		if ref.PID == pid {
+			return ref.Process
+		}
+	}
+	return ""
+}
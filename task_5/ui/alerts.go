@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
+)
+
+const maxAlertLines = 3
+
+var severityRank = map[string]int{
+	models.SeverityCritical: 0,
+	models.SeverityWarning:  1,
+	models.SeverityInfo:     2,
+}
+
+func severityStyle(styles theme.Styles, severity string) lipgloss.Style {
+	switch severity {
+	case models.SeverityCritical:
+		return styles.Error
+	case models.SeverityWarning:
+		return styles.Warning
+	}
+	return styles.Accent
+}
+
+func severityIcon(severity string) string {
+	switch severity {
+	case models.SeverityCritical:
+		return "✖"
+	case models.SeverityWarning:
+		return "⚠"
+	}
+	return "ℹ"
+}
+
+func sortedAlerts(alerts []models.Alert) []models.Alert {
+	sorted := append([]models.Alert(nil), alerts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return severityRank[sorted[i].Severity] < severityRank[sorted[j].Severity]
+	})
+	return sorted
+}
+
+func alertsByConnection(alerts []models.Alert) map[string][]models.Alert {
+	byKey := make(map[string][]models.Alert)
+	for _, alert := range sortedAlerts(alerts) {
+		if alert.Connection != nil {
+			key := alert.Connection.Key()
+			byKey[key] = append(byKey[key], alert)
+		}
+	}
+	return byKey
+}
+
+func renderFlags(styles theme.Styles, alerts []models.Alert) string {
+	var parts []string
+	for _, alert := range alerts {
+		parts = append(parts, severityStyle(styles, alert.Severity).Render(severityIcon(alert.Severity)+" "+alert.Source))
+	}
+	return strings.Join(parts, " ")
+}
+
+// alertLines is the height renderAlerts takes for n alerts, including the
+// overflow line.
+func alertLines(n int) int {
+	if n > maxAlertLines {
+		return maxAlertLines + 1
+	}
+	return n
+}
+
+func (m Model) renderAlerts(limit int) string {
+	alerts := sortedAlerts(m.Alerts)
+
+	var lines []string
+	for i, alert := range alerts {
+		if i == limit {
+			lines = append(lines, m.Styles.Status.Render(fmt.Sprintf("… %d more alerts", len(alerts)-limit)))
+			break
+		}
+		text := fmt.Sprintf(" %s %s [%s] %s", severityIcon(alert.Severity), strings.ToUpper(alert.Severity), alert.Source, alert.Message)
+		lines = append(lines, severityStyle(m.Styles, alert.Severity).MaxWidth(m.Width).Render(text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func gauge(fill float64, width int) string {
+	filled := int(fill*float64(width) + 0.5)
+	filled = max(0, min(width, filled))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+func (m Model) renderConntrack() string {
+	var entries []models.ConntrackEntry
+	if m.Snapshot != nil {
+		entries = append(entries, m.Snapshot.Conntrack...)
+	}
+	if len(entries) == 0 {
+		return m.Styles.Help.Width(max(40, m.Width-4)).Render(m.Styles.Status.Render(
+			"No conntrack entries (start with -conntrack; reading the table needs root or CAP_NET_ADMIN)"))
+	}
+
+	// Entries tied to a local socket first, NATed ones ahead of the rest.
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.PID != "") != (b.PID != "") {
+			return a.PID != ""
+		}
+		return a.NAT() != "" && b.NAT() == ""
+	})
+
+	room := max(3, m.Height-12-alertLines(len(m.Alerts)))
+	format := "%-5s %-12s %-47s %-47s %-9s %8s %-10s %s"
+
+	var lines []string
+	lines = append(lines, m.Styles.HelpKey.Render(fmt.Sprintf(format, "Proto", "State", "Original", "Reply", "NAT", "Timeout", "Mark", "Process")))
+	for i, e := range entries {
+		if len(lines) > room {
+			lines = append(lines, m.Styles.Status.Render(fmt.Sprintf("… %d more", len(entries)-i)))
+			break
+		}
+
+		process := ""
+		switch {
+		case e.Process != "":
+			process = fmt.Sprintf("%s/%s", e.PID, e.Process)
+		case e.PID != "":
+			process = "socket without owner"
+		}
+		line := fmt.Sprintf(format, e.Proto, e.State, e.Original.String(), e.Reply.String(),
+			e.NAT(), e.Timeout, fmt.Sprintf("%#x", e.Mark), process)
+		if flags := strings.Join(e.Flags, ","); flags != "" {
+			line += " [" + flags + "]"
+		}
+
+		style := lipgloss.NewStyle()
+		if e.NAT() != "" {
+			style = m.Styles.Accent
+		}
+		lines = append(lines, style.MaxWidth(max(40, m.Width-8)).Render(line))
+	}
+
+	return m.Styles.Help.Width(max(40, m.Width-4)).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+var keyCounters = []string{
+	"TcpExt.SyncookiesSent",
+	"TcpExt.SyncookiesFailed",
+	"TcpExt.ListenOverflows",
+	"TcpExt.ListenDrops",
+	"TcpExt.TCPBacklogDrop",
+	"Tcp.RetransSegs",
+	"TcpExt.TCPTimeouts",
+	"TcpExt.TCPLostRetransmit",
+	"Tcp.OutRsts",
+	"Tcp.EstabResets",
+	"Tcp.AttemptFails",
+	"Tcp.InErrs",
+	"TcpExt.TCPAbortOnData",
+	"TcpExt.TCPAbortOnTimeout",
+	"Udp.InErrors",
+	"Udp.RcvbufErrors",
+	"Udp.SndbufErrors",
+	"Udp.NoPorts",
+	"Ip.InDiscards",
+	"Ip6.InDiscards",
+}
+
+func counterKey(c models.KernelCounter) string {
+	return c.Host + "|" + c.Name
+}
+
+func (m *Model) updateCounters(snapshot *models.Snapshot) {
+	if snapshot == nil || !snapshot.Time.After(m.CounterTime) {
+		return
+	}
+
+	deltas := make(map[string]int64, len(snapshot.Counters))
+	current := make(map[string]int64, len(snapshot.Counters))
+	for _, c := range snapshot.Counters {
+		key := counterKey(c)
+		current[key] = c.Value
+		if prev, ok := m.CounterPrev[key]; ok && c.Value >= prev {
+			deltas[key] = c.Value - prev
+		}
+	}
+
+	m.CounterPrev = current
+	m.CounterDeltas = deltas
+	m.CounterTime = snapshot.Time
+}
+
+func (m Model) renderCounters() string {
+	if m.Snapshot == nil || len(m.Snapshot.Counters) == 0 {
+		return m.Styles.Status.Render("Kernel counters unavailable")
+	}
+
+	host := ""
+	if m.HostTab > 0 && m.HostTab <= len(m.Hosts) {
+		host = m.Hosts[m.HostTab-1]
+	}
+
+	byName := make(map[string][]models.KernelCounter)
+	var changing []models.KernelCounter
+	for _, c := range m.Snapshot.Counters {
+		if host != "" && c.Host != host {
+			continue
+		}
+		byName[c.Name] = append(byName[c.Name], c)
+		if m.CounterDeltas[counterKey(c)] > 0 {
+			changing = append(changing, c)
+		}
+	}
+
+	var lines []string
+	lines = append(lines, m.Styles.HelpKey.Render(fmt.Sprintf("%-34s %14s %10s", "Key counters", "total", "Δ/tick")))
+	for _, name := range keyCounters {
+		for _, c := range byName[name] {
+			lines = append(lines, m.counterLine(c))
+		}
+	}
+
+	sort.Slice(changing, func(i, j int) bool {
+		return m.CounterDeltas[counterKey(changing[i])] > m.CounterDeltas[counterKey(changing[j])]
+	})
+
+	room := max(3, m.Height-len(lines)-10)
+	lines = append(lines, "", m.Styles.HelpKey.Render(fmt.Sprintf("%-34s %14s %10s", "Rising since last tick", "total", "Δ/tick")))
+	shown := 0
+	for _, c := range changing {
+		if shown == room {
+			lines = append(lines, m.Styles.Status.Render(fmt.Sprintf("… %d more", len(changing)-shown)))
+			break
+		}
+		lines = append(lines, m.counterLine(c))
+		shown++
+	}
+	if len(changing) == 0 {
+		lines = append(lines, m.Styles.Status.Render("nothing changed"))
+	}
+
+	return m.Styles.Help.Width(max(40, m.Width-4)).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) counterLine(c models.KernelCounter) string {
+	name := c.Name
+	if c.Host != "" && len(m.Hosts) > 1 && m.HostTab == 0 {
+		name = c.Host + " " + name
+	}
+
+	delta := m.CounterDeltas[counterKey(c)]
+	line := fmt.Sprintf("%-34.34s %14d %10s", name, c.Value, fmt.Sprintf("+%d", delta))
+	if c.Value != 0 && delta > 0 {
+		return m.Styles.Warning.Render(line)
+	}
+	return line
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+type countEntry struct {
+	name  string
+	count int
+}
+
+func topCounts(counts map[string]int, n int) []countEntry {
+	entries := make([]countEntry, 0, len(counts))
+	for name, count := range counts {
+		entries = append(entries, countEntry{name, count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].name < entries[j].name
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+func (m Model) dashboardConnections() []models.ConnectionItem {
+	if m.Snapshot == nil {
+		return nil
+	}
+	if m.HostTab == 0 || m.HostTab > len(m.Hosts) {
+		return m.Snapshot.Connections
+	}
+
+	var conns []models.ConnectionItem
+	for _, conn := range m.Snapshot.Connections {
+		if conn.Host == m.Hosts[m.HostTab-1] {
+			conns = append(conns, conn)
+		}
+	}
+	return conns
+}
+
+func (m Model) renderDashboard() string {
+	conns := m.dashboardConnections()
+
+	protos := make(map[string]int)
+	states := make(map[string]int)
+	ports := make(map[string]int)
+	remotes := make(map[string]int)
+	processes := make(map[string]int)
+
+	for _, conn := range conns {
+		protos[conn.Proto]++
+		states[conn.State]++
+		if conn.IsListening() {
+			ports[conn.Proto+" "+strconv.Itoa(conn.LocalPort)]++
+		} else if conn.RemotePort != 0 {
+			remotes[conn.RemoteIP]++
+		}
+		name := conn.Process
+		if name == "" {
+			name = "unknown"
+		}
+		processes[name+" ("+conn.PID+")"]++
+	}
+
+	columns := 1
+	switch {
+	case m.Width >= 120:
+		columns = 3
+	case m.Width >= 80:
+		columns = 2
+	}
+
+	const panelCount = 6
+	panelRows := (panelCount + columns - 1) / columns
+	rows := max(3, min(15, (m.Height-8)/panelRows-4))
+	width := max(24, (m.Width-2)/columns-2)
+
+	panels := []string{
+		m.renderCountPanel("Protocols", topCounts(protos, rows), width),
+		m.renderCountPanel("States", topCounts(states, rows), width),
+		m.renderCountPanel("Listening ports", topCounts(ports, rows), width),
+		m.renderCountPanel("Remote hosts", topCounts(remotes, rows), width),
+		m.renderCountPanel("Processes by sockets", topCounts(processes, rows), width),
+		m.renderSockstatPanel(width),
+	}
+
+	var grid []string
+	for i := 0; i < len(panels); i += columns {
+		end := min(i+columns, len(panels))
+		grid = append(grid, lipgloss.JoinHorizontal(lipgloss.Top, panels[i:end]...))
+	}
+
+	header := m.Styles.Status.Render(fmt.Sprintf("%d sockets on %s", len(conns), m.hostTabName(m.HostTab)))
+	return header + "\n" + lipgloss.JoinVertical(lipgloss.Left, grid...)
+}
+
+func (m Model) renderCountPanel(title string, entries []countEntry, width int) string {
+	inner := width - 4
+	lines := []string{m.Styles.HelpKey.Render(title)}
+
+	peak := 0
+	for _, e := range entries {
+		peak = max(peak, e.count)
+	}
+
+	nameWidth := max(8, inner/2)
+	barWidth := max(1, inner-nameWidth-7)
+	for _, e := range entries {
+		bar := ""
+		if peak > 0 {
+			bar = strings.Repeat("█", max(1, e.count*barWidth/peak))
+		}
+		name := e.name
+		if len(name) > nameWidth {
+			name = name[:nameWidth-1] + "…"
+		}
+		lines = append(lines, fmt.Sprintf("%-*s %5d %s", nameWidth, name, e.count, m.Styles.Accent.Render(bar)))
+	}
+	if len(entries) == 0 {
+		lines = append(lines, m.Styles.Status.Render("none"))
+	}
+
+	return m.Styles.Help.Width(width).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) renderSockstatPanel(width int) string {
+	lines := []string{m.Styles.HelpKey.Render("Socket summary")}
+
+	if m.Snapshot != nil {
+		host := ""
+		if m.HostTab > 0 && m.HostTab <= len(m.Hosts) {
+			host = m.Hosts[m.HostTab-1]
+		}
+		for _, entry := range m.Snapshot.Sockstat {
+			if (host != "" && entry.Host != host) || allZero(entry.Counters) {
+				continue
+			}
+			line := entry.String()
+			if host == "" && entry.Host != "" {
+				line = entry.Host + " " + line
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 {
+		lines = append(lines, m.Styles.Status.Render("unavailable"))
+	}
+
+	return m.Styles.Help.Width(width).Render(strings.Join(lines, "\n"))
+}
+
+func allZero(counters []models.Counter) bool {
+	for _, c := range counters {
+		if c.Value != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/services"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
+)
+
+type connectionDelegate struct {
+	list.DefaultDelegate
+	styles      theme.Styles
+	showHost    bool
+	showMetrics bool
+	flags       map[string][]models.Alert
+	names       map[string]string
+
+	showServices bool
+}
+
+func newConnectionDelegate(styles theme.Styles) connectionDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles = styles.ItemStyles
+	return connectionDelegate{DefaultDelegate: d, styles: styles}
+}
+
+func (d connectionDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	conn, ok := item.(models.ConnectionItem)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	if m.Width() <= 0 {
+		return
+	}
+
+	s := d.Styles
+	var (
+		isSelected  = index == m.Index()
+		emptyFilter = m.FilterState() == list.Filtering && m.FilterValue() == ""
+	)
+
+	titleStyle := s.NormalTitle.Foreground(d.styles.State(conn.State).GetForeground())
+	descStyle := s.NormalDesc
+	if emptyFilter {
+		titleStyle = s.DimmedTitle
+		descStyle = s.DimmedDesc
+	} else if isSelected && m.FilterState() != list.Filtering {
+		titleStyle = s.SelectedTitle
+		descStyle = s.SelectedDesc
+	}
+
+	if d.styles.Theme.Monochrome && !emptyFilter {
+		titleStyle = titleStyle.Inherit(d.styles.State(conn.State))
+	}
+
+	width := m.Width()
+	titleText := d.title(conn)
+	if d.showHost {
+		titleText = fmt.Sprintf("%-14.14s │ %s", conn.Host, titleText)
+	}
+	if isSelected && len(conn.RateHistory) > 0 {
+		titleText += "  " + sparkline(conn.RateHistory)
+	}
+	title := titleStyle.MaxWidth(width).Render(titleText)
+
+	descText := conn.Description()
+	if d.showMetrics {
+		descText += metricsColumns(conn)
+	}
+	if geo := geoColumn(conn); geo != "" {
+		descText += " | " + geo
+	}
+	desc := descStyle.Render(descText)
+	if !emptyFilter {
+		if indicators := d.indicators(conn); indicators != "" {
+			desc += " " + indicators
+		}
+	}
+	desc = lipgloss.NewStyle().MaxWidth(width).Render(desc)
+
+	fmt.Fprintf(w, "%s\n%s", title, desc)
+}
+
+func (d connectionDelegate) title(conn models.ConnectionItem) string {
+	local, remote := conn.Local, conn.Remote
+	if name, ok := d.names[conn.RemoteIP]; ok {
+		remote = fmt.Sprintf("%s:%d", name, conn.RemotePort)
+	}
+	if d.showServices {
+		if conn.LocalService != "" {
+			local += "/" + conn.LocalService
+		}
+		if conn.RemoteService != "" {
+			remote += "/" + conn.RemoteService
+		}
+	}
+	return fmt.Sprintf("%s %s → %s", conn.Proto, local, remote)
+}
+
+func (d connectionDelegate) indicators(conn models.ConnectionItem) string {
+	var parts []string
+
+	if flags := d.flags[conn.Key()]; len(flags) > 0 {
+		parts = append(parts, renderFlags(d.styles, flags))
+	}
+
+	scope := connections.ConnectionScope(conn)
+	parts = append(parts, d.styles.Scope(scope).Render("["+scope+"]"))
+
+	if conn.IsListening() && scope == "any" {
+		parts = append(parts, d.styles.Warning.Render("◉ exposed"))
+	}
+
+	if conn.HasBacklog() {
+		backlog := fmt.Sprintf("%s %d/%d", gauge(conn.BacklogFill(), 8), conn.RxQueue, conn.TxQueue)
+		if conn.BacklogFill() >= backlogWarnFill {
+			parts = append(parts, d.styles.Warning.Render(backlog))
+		} else {
+			parts = append(parts, d.styles.Accent.Render(backlog))
+		}
+	}
+
+	if port, service, ok := services.Default().Abused(conn.Proto, conn.RemotePort, conn.LocalPort); ok {
+		parts = append(parts, d.styles.Warning.Render(fmt.Sprintf("☠ %d/%s", port, service)))
+	}
+
+	if conn.IsPrivilegedPort() {
+		parts = append(parts, d.styles.Badge.Render(" ⚑ :"+strconv.Itoa(conn.LocalPort)+" "))
+	}
+
+	if conn.IsRootOwned() {
+		parts = append(parts, d.styles.Badge.Render(" ▲ root "))
+	}
+
+	if conn.TxRate > 0 || conn.RxRate > 0 {
+		parts = append(parts, d.styles.Accent.Render("↑"+formatRate(conn.TxRate)+" ↓"+formatRate(conn.RxRate)))
+	}
+
+	if hasHighRetransmits(conn) {
+		parts = append(parts, d.styles.Warning.Render(fmt.Sprintf("⚠ retx %.1f%%", conn.TCP.RetransmitRatio()*100)))
+	}
+
+	if conn.Conntrack != nil {
+		if nat := conn.Conntrack.NAT(); nat != "" {
+			parts = append(parts, d.styles.Accent.Render("⤳ "+nat))
+		}
+	}
+
+	if conn.PeerHost != "" {
+		peer := conn.PeerHost
+		if conn.PeerProc != "" {
+			peer += "/" + conn.PeerProc
+		}
+		parts = append(parts, d.styles.Accent.Render("⇄ "+peer))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func geoColumn(conn models.ConnectionItem) string {
+	var parts []string
+	if conn.Country != "" {
+		place := conn.Country
+		if conn.City != "" {
+			place += " " + conn.City
+		}
+		parts = append(parts, place)
+	}
+	if conn.ASN != 0 {
+		parts = append(parts, fmt.Sprintf("AS%d %s", conn.ASN, conn.ASOrg))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func metricsColumns(conn models.ConnectionItem) string {
+	t := conn.TCP
+	if t == nil {
+		return fmt.Sprintf(" | %-10s %-8s %-7s %-9s %-9s", "rtt -", "cwnd -", "retx -", "tx -", "rx -")
+	}
+	return fmt.Sprintf(" | rtt %-6s cwnd %-3d retx %-3d tx %-8s rx %-8s",
+		formatMillis(t.RTT), t.Cwnd, t.Retransmits, formatBytes(t.BytesSent), formatBytes(t.BytesReceived))
+}
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// highRetransmitRatio marks connections where more than this share of
+// sent segments had to be retransmitted; connections with fewer than
+// minSegsForRatio segments sent are not judged.
+const (
+	highRetransmitRatio = 0.02
+	minSegsForRatio     = 100
+)
+
+// backlogWarnFill highlights listen queues filled beyond this share.
+const backlogWarnFill = 0.9
+
+// detailsHeight is the number of rows, borders included, reserved for the
+// details pane; longer content is cut to fit.
+const detailsHeight = 21
+
+func hasHighRetransmits(conn models.ConnectionItem) bool {
+	return conn.TCP != nil && conn.TCP.SegsOut >= minSegsForRatio && conn.TCP.RetransmitRatio() >= highRetransmitRatio
+}
+
+func (m Model) selectedConnection() (models.ConnectionItem, bool) {
+	conn, ok := m.ConnectionsList.SelectedItem().(models.ConnectionItem)
+	return conn, ok
+}
+
+func (m Model) renderDetails() string {
+	conn, ok := m.selectedConnection()
+	if !ok {
+		return m.detailsPane([]string{"No connection selected"})
+	}
+
+	var lines []string
+	field := func(label, value string) {
+		lines = append(lines, m.Styles.HelpKey.Render(fmt.Sprintf("%-14s", label))+value)
+	}
+
+	field("Protocol", conn.Proto)
+	field("Local", withService(conn.Local, conn.LocalService))
+	field("Remote", withService(conn.Remote, conn.RemoteService))
+	if name, ok := m.Names[conn.RemoteIP]; ok {
+		field("Remote name", name)
+	}
+	field("State", conn.State)
+	field("Scope", connections.ConnectionScope(conn))
+	field("PID", conn.PID)
+	field("Process", conn.Process)
+	field("User", fmt.Sprintf("%s (uid %s)", conn.User, conn.UID))
+	field("Inode", conn.Inode)
+	if conn.HasBacklog() {
+		field("Accept queue", fmt.Sprintf("%s %d/%d (%.0f%%)", gauge(conn.BacklogFill(), 20), conn.RxQueue, conn.TxQueue, conn.BacklogFill()*100))
+	} else {
+		field("Queues", fmt.Sprintf("recv %d / send %d bytes", conn.RxQueue, conn.TxQueue))
+	}
+	if conn.Country != "" {
+		location := conn.Country
+		if conn.City != "" {
+			location = conn.City + ", " + conn.Country
+		}
+		field("Location", location)
+	}
+	if conn.ASN != 0 {
+		field("ASN", fmt.Sprintf("AS%d %s", conn.ASN, conn.ASOrg))
+	}
+	if conn.Exe != "" {
+		field("Executable", conn.Exe)
+		if problems := exeProblems(conn); len(problems) > 0 {
+			field("Integrity", m.Styles.Error.Render(strings.Join(problems, ", ")))
+		} else {
+			field("Integrity", "ok")
+		}
+		if conn.ExeSHA256 != "" {
+			field("SHA-256", conn.ExeSHA256)
+		}
+	}
+	for _, alert := range alertsByConnection(m.Alerts)[conn.Key()] {
+		field("Flag", severityStyle(m.Styles, alert.Severity).Render(
+			fmt.Sprintf("%s %s %s", severityIcon(alert.Severity), strings.ToUpper(alert.Severity), alert.Source))+" "+alert.Message)
+		for _, evidence := range alert.Evidence {
+			field("", "  · "+evidence)
+		}
+	}
+	if conn.Host != "" {
+		field("Host", conn.Host)
+	}
+	if conn.Iface != "" {
+		route := conn.Iface + " (on-link)"
+		if conn.NextHop != "" {
+			route = conn.Iface + " via " + conn.NextHop
+		}
+		field("Route", route)
+		if conn.NeighMAC != "" {
+			field("Next-hop MAC", conn.NeighMAC)
+		}
+	}
+	if ct := conn.Conntrack; ct != nil {
+		summary := strings.TrimSpace(fmt.Sprintf("%s %s", ct.State, ct.NAT()))
+		field("Conntrack", fmt.Sprintf("%s  timeout %s  mark %#x", summary, ct.Timeout, ct.Mark))
+		field("  original", ct.Original.String())
+		field("  reply", ct.Reply.String())
+	}
+	if conn.PeerHost != "" {
+		field("Peer", fmt.Sprintf("%s pid %s %s", conn.PeerHost, conn.PeerPID, conn.PeerProc))
+	}
+
+	if t := conn.TCP; t != nil {
+		lines = append(lines, "")
+		field("RTT", fmt.Sprintf("%s ± %s", formatMillis(t.RTT), formatMillis(t.RTTVar)))
+		field("Cwnd", fmt.Sprintf("%d segments", t.Cwnd))
+
+		retrans := fmt.Sprintf("%d of %d segments (%.2f%%)", t.Retransmits, t.SegsOut, t.RetransmitRatio()*100)
+		if hasHighRetransmits(conn) {
+			retrans = m.Styles.Warning.Render(retrans)
+		}
+		field("Retransmits", retrans)
+		field("Sent", formatBytes(t.BytesSent))
+		field("Received", formatBytes(t.BytesReceived))
+		field("Delivery rate", formatRate(float64(t.DeliveryRate)))
+		field("Last send", formatMillis(t.LastSend)+" ago")
+		field("Last receive", formatMillis(t.LastReceive)+" ago")
+		field("Rate", fmt.Sprintf("↑ %s ↓ %s  %s", formatRate(conn.TxRate), formatRate(conn.RxRate), sparkline(conn.RateHistory)))
+	}
+
+	if proc, ok := m.processRate(conn); ok {
+		field("Process rate", fmt.Sprintf("↑ %s ↓ %s over %d sockets  %s",
+			formatRate(proc.TxRate), formatRate(proc.RxRate), proc.Connections, sparkline(proc.History)))
+	}
+
+	return m.detailsPane(lines)
+}
+
+// detailsPane wraps lines to the pane width and cuts them so the pane never
+// takes more than detailsHeight rows.
+func (m Model) detailsPane(lines []string) string {
+	style := m.Styles.Help.Width(max(40, m.Width-4))
+	body := lipgloss.NewStyle().Width(style.GetWidth() - style.GetHorizontalPadding()).Render(strings.Join(lines, "\n"))
+	rows := strings.Split(body, "\n")
+	if limit := detailsHeight - style.GetVerticalFrameSize(); len(rows) > limit {
+		hidden := len(rows) - limit + 1
+		rows = append(rows[:limit-1], fmt.Sprintf("… %d more lines", hidden))
+	}
+	return style.Render(strings.Join(rows, "\n"))
+}
+
+func exeProblems(conn models.ConnectionItem) []string {
+	var problems []string
+	if conn.ExeDeleted {
+		problems = append(problems, "deleted from disk")
+	}
+	if conn.ExeReplaced {
+		problems = append(problems, "replaced on disk since start")
+	}
+	if conn.ExeUnsafeDir {
+		problems = append(problems, "runs from a world-writable/tmp directory")
+	}
+	return problems
+}
+
+func withService(addr, service string) string {
+	if service == "" {
+		return addr
+	}
+	return addr + " (" + service + ")"
+}
+
+func (m Model) processRate(conn models.ConnectionItem) (models.ProcessRate, bool) {
+	for _, proc := range m.ProcessRates {
+		if proc.PID == conn.PID && proc.Host == conn.Host {
+			return proc, true
+		}
+	}
+	return models.ProcessRate{}, false
+}
+
+func (m Model) topTalkers(n int) string {
+	var parts []string
+	for _, proc := range m.ProcessRates {
+		if len(parts) == n || proc.TxRate+proc.RxRate == 0 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s ↑%s ↓%s", proc.Process, formatRate(proc.TxRate), formatRate(proc.RxRate)))
+	}
+	if len(parts) == 0 {
+		return "idle"
+	}
+	return strings.Join(parts, ", ")
+}
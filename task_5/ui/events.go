@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+const maxEvents = 500
+
+func appendEvents(events, added []models.Event) []models.Event {
+	events = append(events, added...)
+	if len(events) > maxEvents {
+		events = append([]models.Event(nil), events[len(events)-maxEvents:]...)
+	}
+	return events
+}
+
+func (m Model) renderEvents() string {
+	if len(m.Events) == 0 {
+		return m.Styles.Help.Width(max(40, m.Width-4)).Render(m.Styles.Status.Render("No events yet"))
+	}
+
+	room := max(3, m.Height-12-alertLines(len(m.Alerts)))
+
+	var lines []string
+	lines = append(lines, m.Styles.HelpKey.Render(fmt.Sprintf("%-8s  %-9s  %-12s  %s", "Time", "Severity", "Source", "Message")))
+	for i := len(m.Events) - 1; i >= 0; i-- {
+		if len(lines) > room {
+			lines = append(lines, m.Styles.Status.Render(fmt.Sprintf("… %d older", i+1)))
+			break
+		}
+		event := m.Events[i]
+		line := fmt.Sprintf("%-8s  %-9s  %-12.12s  %s",
+			event.Time.Format("15:04:05"), strings.ToUpper(event.Severity), event.Source, event.Message)
+		lines = append(lines, severityStyle(m.Styles, event.Severity).MaxWidth(max(40, m.Width-8)).Render(line))
+	}
+
+	return m.Styles.Help.Width(max(40, m.Width-4)).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// filterKeys are the field names the filter language understands. Keys in
+// filterContains match on a substring, the rest need the whole value.
+var (
+	filterKeys     = map[string]bool{"port": true, "service": true, "exe": true, "sha256": true, "country": true, "city": true, "asn": true, "org": true, "iface": true}
+	filterContains = map[string]bool{"exe": true, "sha256": true, "city": true, "org": true}
+)
+
+type fieldTerm struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// filterItems extends the list's fuzzy filter with "key:value" and
+// "key:!value" terms, e.g. "country:!RU service:ssh". Plain words are still
+// matched fuzzily against whatever the field terms let through.
+func filterItems(term string, targets []string) []list.Rank {
+	var terms []fieldTerm
+	var plain []string
+	for _, word := range strings.Fields(term) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || !filterKeys[strings.ToLower(key)] {
+			plain = append(plain, word)
+			continue
+		}
+		t := fieldTerm{key: strings.ToLower(key), value: strings.ToLower(value)}
+		if strings.HasPrefix(t.value, "!") {
+			t.negate = true
+			t.value = t.value[1:]
+		}
+		terms = append(terms, t)
+	}
+
+	if len(terms) == 0 {
+		return list.DefaultFilter(term, targets)
+	}
+
+	var indexes []int
+	var subset []string
+	for i, target := range targets {
+		if matchesTerms(target, terms) {
+			indexes = append(indexes, i)
+			subset = append(subset, target)
+		}
+	}
+
+	if len(plain) == 0 {
+		ranks := make([]list.Rank, len(indexes))
+		for i, index := range indexes {
+			ranks[i] = list.Rank{Index: index}
+		}
+		return ranks
+	}
+
+	ranks := list.DefaultFilter(strings.Join(plain, " "), subset)
+	for i := range ranks {
+		ranks[i].Index = indexes[ranks[i].Index]
+	}
+	return ranks
+}
+
+func matchesTerms(target string, terms []fieldTerm) bool {
+	fields := make(map[string][]string)
+	for _, token := range strings.Fields(target) {
+		if key, value, ok := strings.Cut(token, ":"); ok && filterKeys[key] {
+			fields[key] = append(fields[key], strings.ToLower(value))
+		}
+	}
+
+	for _, t := range terms {
+		if matchesAny(t, fields[t.key]) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether any of a connection's values for the key
+// match, so "port:22" hits either end and "port:!22" neither.
+func matchesAny(t fieldTerm, values []string) bool {
+	for _, value := range values {
+		if filterContains[t.key] {
+			if t.value != "" && strings.Contains(value, t.value) {
+				return true
+			}
+		} else if value == t.value {
+			return true
+		}
+	}
+	return false
+}
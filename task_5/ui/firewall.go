@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mizerael/infsec_ssu/task_5/firewall"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/muesli/termenv"
+)
+
+func (m Model) openFirewall() (Model, tea.Cmd) {
+	if m.ActiveView != models.ViewConnections {
+		return m, nil
+	}
+	conn, ok := m.selectedConnection()
+	if !ok {
+		return m, nil
+	}
+
+	draft := &models.FirewallDraft{Connection: conn}
+	if conn.IsListening() || conn.RemotePort == 0 {
+		// Listeners have no remote address to match on.
+		draft.Match = slices.Index(firewall.Matches, firewall.MatchLocalPort)
+	}
+	m.Firewall = draft
+	return m, nil
+}
+
+func (m Model) firewallSpec(draft *models.FirewallDraft) firewall.Spec {
+	return firewall.Spec{
+		Backend:    firewall.Backends[draft.Backend],
+		Allow:      draft.Allow,
+		Match:      firewall.Matches[draft.Match],
+		Connection: draft.Connection,
+		Prefix:     draft.Prefix,
+	}
+}
+
+// cidrPrefix is the prefix length the draft's remote-cidr rule uses, and
+// the longest one the remote address allows. ok is false without a usable
+// remote address.
+func cidrPrefix(draft *models.FirewallDraft) (prefix, bits int, ok bool) {
+	ip := net.ParseIP(draft.Connection.RemoteIP)
+	if ip == nil {
+		return 0, 0, false
+	}
+	prefix, bits = firewall.DefaultPrefix(ip)
+	if draft.Prefix != 0 {
+		prefix = draft.Prefix
+	}
+	return prefix, bits, true
+}
+
+func (m Model) handleFirewallMode(msg tea.KeyMsg) (Model, tea.Cmd) {
+	draft := *m.Firewall
+	draft.Status = ""
+
+	if draft.Confirm {
+		draft.Confirm = false
+		if s := msg.String(); s == "y" || s == "Y" || s == "enter" {
+			cmds, err := firewall.Generate(m.firewallSpec(&draft))
+			if err != nil {
+				draft.Status = "Error: " + err.Error()
+				m.Firewall = &draft
+				return m, nil
+			}
+			draft.Status = "Applying..."
+			m.Firewall = &draft
+			return m, m.applyFirewallCmd(cmds)
+		}
+		draft.Status = "Not applied"
+		m.Firewall = &draft
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.Firewall = nil
+		return m, nil
+
+	case "b":
+		draft.Backend = (draft.Backend + 1) % len(firewall.Backends)
+
+	case "a":
+		draft.Allow = !draft.Allow
+
+	case "left", "h", "shift+tab":
+		draft.Match = (draft.Match + len(firewall.Matches) - 1) % len(firewall.Matches)
+
+	case "right", "l", "tab":
+		draft.Match = (draft.Match + 1) % len(firewall.Matches)
+
+	case "+", "=", "-":
+		if firewall.Matches[draft.Match] != firewall.MatchRemoteCIDR {
+			break
+		}
+		prefix, bits, ok := cidrPrefix(&draft)
+		if !ok {
+			break
+		}
+		if msg.String() == "-" {
+			prefix--
+		} else {
+			prefix++
+		}
+		draft.Prefix = max(1, min(prefix, bits))
+
+	case "c", "w", "x":
+		cmds, err := firewall.Generate(m.firewallSpec(&draft))
+		if err != nil {
+			draft.Status = "Error: " + err.Error()
+			break
+		}
+
+		switch msg.String() {
+		case "c":
+			termenv.Copy(firewall.Script(cmds))
+			draft.Status = fmt.Sprintf("Copied %d commands to the clipboard", len(cmds))
+		case "w":
+			name := "stattui-firewall-" + time.Now().Format("20060102-150405") + ".sh"
+			if err := os.WriteFile(name, []byte(firewall.Script(cmds)), 0o700); err != nil {
+				draft.Status = "Error: " + err.Error()
+			} else {
+				draft.Status = "Wrote " + name
+			}
+		case "x":
+			if m.Actions == nil || !m.Actions.CanApplyFirewall() {
+				draft.Status = "Applying is disabled (start with -apply-firewall on the local host)"
+			} else {
+				draft.Confirm = true
+			}
+		}
+	}
+
+	m.Firewall = &draft
+	return m, nil
+}
+
+func (m Model) applyFirewallCmd(cmds []firewall.Command) tea.Cmd {
+	runner := m.Actions
+	argv := make([][]string, len(cmds))
+	for i, cmd := range cmds {
+		argv[i] = cmd
+	}
+	return func() tea.Msg {
+		status, err := runner.ApplyFirewall(argv)
+		done := models.ActionDoneMsg{Status: status}
+		if err != nil {
+			done.Err = err.Error()
+		}
+		return done
+	}
+}
+
+func (m Model) renderFirewall() string {
+	draft := m.Firewall
+	conn := draft.Connection
+	var s strings.Builder
+
+	titleStyle := m.Styles.Title.
+		Width(m.Width - 1).
+		Align(lipgloss.Center)
+
+	s.WriteString(titleStyle.Render("Firewall rules"))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("%s %s → %s  %s  uid %s (%s)\n\n", conn.Proto, conn.Local, conn.Remote, conn.State, conn.UID, conn.Process))
+
+	verdict := "block"
+	if draft.Allow {
+		verdict = "allow"
+	}
+	s.WriteString(m.Styles.HelpKey.Render("Backend: ") + m.choices(firewall.Backends, draft.Backend) + "\n")
+	s.WriteString(m.Styles.HelpKey.Render("Match:   ") + m.choices(firewall.Matches, draft.Match) + "\n")
+	if firewall.Matches[draft.Match] == firewall.MatchRemoteCIDR {
+		if prefix, _, ok := cidrPrefix(draft); ok {
+			s.WriteString(m.Styles.HelpKey.Render("Prefix:  ") + fmt.Sprintf("/%d", prefix) + "\n")
+		}
+	}
+	s.WriteString(m.Styles.HelpKey.Render("Action:  ") + verdict + "\n\n")
+
+	spec := m.firewallSpec(draft)
+	cmds, err := firewall.Generate(spec)
+	if err != nil {
+		s.WriteString(m.Styles.Error.Render("Cannot generate rules: " + err.Error()))
+		s.WriteString("\n\n")
+	} else {
+		lines := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			lines[i] = cmd.String()
+		}
+		box := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			Padding(0, 1).
+			MaxWidth(m.Width - 1)
+		s.WriteString(box.Render(strings.Join(lines, "\n")))
+		s.WriteString("\n")
+		if draft.Allow && spec.Backend == firewall.Nftables {
+			s.WriteString(m.Styles.Warning.Render("Note: accept only ends the " + firewall.Table + " table's chain; a drop in another table still applies."))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	switch {
+	case draft.Confirm:
+		prompt := fmt.Sprintf("Run these %d commands now? (y/Enter to apply, any other key to cancel)", len(cmds))
+		if m.Actions.DryRun() {
+			prompt += " [dry run]"
+		}
+		s.WriteString(m.Styles.Warning.Render(prompt))
+		s.WriteString("\n\n")
+	case draft.Status != "":
+		s.WriteString(draft.Status)
+		s.WriteString("\n\n")
+	}
+
+	hint := "(b backend • ←/→ match • +/- prefix • a block/allow • c copy • w write script"
+	if m.Actions != nil && m.Actions.CanApplyFirewall() {
+		hint += " • x apply"
+	}
+	s.WriteString(hint + " • Esc close)")
+
+	return s.String()
+}
+
+func (m Model) choices(options []string, selected int) string {
+	parts := make([]string, len(options))
+	for i, option := range options {
+		if i == selected {
+			parts[i] = m.Styles.Badge.Render(" " + option + " ")
+		} else {
+			parts[i] = " " + option + " "
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mizerael/infsec_ssu/task_5/models"
+)
+
+// hostsOf lists every host in the unfiltered snapshot, plus agents that
+// report health but have no connections, so tabs don't come and go with
+// the state filter.
+func hostsOf(snapshot *models.Snapshot, health []models.HostHealth) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	add := func(host string) {
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	if snapshot != nil {
+		for _, conn := range snapshot.Connections {
+			add(conn.Host)
+		}
+	}
+	for _, h := range health {
+		add(h.Host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// setHosts replaces the host list, keeping the selected tab on the same
+// host. If that host is gone the view falls back to all hosts.
+func (m *Model) setHosts(hosts []string) {
+	selected := ""
+	if m.HostTab > 0 && m.HostTab <= len(m.Hosts) {
+		selected = m.Hosts[m.HostTab-1]
+	}
+	m.Hosts = hosts
+	m.HostTab = 0
+	for i, host := range hosts {
+		if host == selected {
+			m.HostTab = i + 1
+		}
+	}
+}
+
+func (m Model) hostTabName(tab int) string {
+	if tab == 0 || tab > len(m.Hosts) {
+		return "all hosts"
+	}
+	return m.Hosts[tab-1]
+}
+
+func (m *Model) applyItems() tea.Cmd {
+	m.ConnectionsList.SetDelegate(m.delegate())
+
+	var items []list.Item
+	for _, conn := range m.AllConnections {
+		if m.HostTab > 0 && m.HostTab <= len(m.Hosts) && conn.Host != m.Hosts[m.HostTab-1] {
+			continue
+		}
+		items = append(items, conn)
+	}
+
+	if m.SortByRate {
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].(models.ConnectionItem), items[j].(models.ConnectionItem)
+			return a.TxRate+a.RxRate > b.TxRate+b.RxRate
+		})
+	}
+
+	return m.ConnectionsList.SetItems(items)
+}
+
+func (m Model) delegate() connectionDelegate {
+	delegate := newConnectionDelegate(m.Styles)
+	delegate.showHost = len(m.Hosts) > 1 && m.HostTab == 0
+	delegate.showMetrics = m.ShowMetrics
+	delegate.flags = alertsByConnection(m.Alerts)
+	if m.ShowNames {
+		delegate.names = m.Names
+	}
+	delegate.showServices = m.ShowServices
+	return delegate
+}
+
+func (m Model) renderHostTabs() string {
+	tabs := []string{"All"}
+	tabs = append(tabs, m.Hosts...)
+
+	var rendered []string
+	for i, name := range tabs {
+		if i == m.HostTab {
+			rendered = append(rendered, m.Styles.ListTitle.Render(name))
+		} else {
+			rendered = append(rendered, m.Styles.Status.Render(name))
+		}
+	}
+	return " " + strings.Join(rendered, " ")
+}
+
+func (m Model) renderHealth() string {
+	var parts []string
+	for _, h := range m.Health {
+		name := h.Host
+		if name == "" {
+			name = h.Addr
+		}
+
+		seen := "never"
+		if !h.LastSeen.IsZero() {
+			seen = time.Since(h.LastSeen).Truncate(time.Second).String() + " ago"
+		}
+
+		part := fmt.Sprintf("%s: %d conns, seen %s", name, h.Connections, seen)
+		if h.Error != "" {
+			parts = append(parts, m.Styles.Error.Render("✗ "+part+" ("+h.Error+")"))
+		} else {
+			parts = append(parts, m.Styles.Status.Render("✓ "+part))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m Model) renderInterfaces() string {
+	if m.Snapshot == nil || len(m.Snapshot.Interfaces) == 0 {
+		return m.Styles.Help.Width(max(40, m.Width-4)).Render(m.Styles.Status.Render(
+			"No interface counters (only available for the local host)"))
+	}
+
+	conns := make(map[string]int)
+	for _, conn := range m.AllConnections {
+		if conn.Iface != "" {
+			conns[conn.Iface]++
+		}
+	}
+
+	format := "%-12s %10s %10s  %-30s %10s %10s %8s %8s %6s"
+	var lines []string
+	lines = append(lines, m.Styles.HelpKey.Render(fmt.Sprintf(format,
+		"Interface", "↓ Rx", "↑ Tx", "History", "Received", "Sent", "Errors", "Drops", "Conns")))
+	for _, iface := range m.Snapshot.Interfaces {
+		line := fmt.Sprintf(format,
+			iface.Name,
+			formatRate(iface.RxRate),
+			formatRate(iface.TxRate),
+			sparkline(iface.RateHistory),
+			formatBytes(iface.RxBytes),
+			formatBytes(iface.TxBytes),
+			fmt.Sprintf("%d", iface.RxErrors+iface.TxErrors),
+			fmt.Sprintf("%d", iface.RxDropped+iface.TxDropped),
+			fmt.Sprintf("%d", conns[iface.Name]),
+		)
+
+		style := lipgloss.NewStyle()
+		switch {
+		case iface.RxErrors+iface.TxErrors > 0:
+			style = m.Styles.Warning
+		case iface.RxRate+iface.TxRate > 0:
+			style = m.Styles.Accent
+		}
+		lines = append(lines, style.MaxWidth(max(40, m.Width-8)).Render(line))
+	}
+
+	return m.Styles.Help.Width(max(40, m.Width-4)).Render(strings.Join(lines, "\n"))
+}
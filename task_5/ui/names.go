@@ -0,0 +1,20 @@
+package ui
+
+import "github.com/mizerael/infsec_ssu/task_5/connections"
+
+// resolveNames picks up cached names and queues lookups for the rest; the
+// answers come back as NameResolvedMsg.
+func (m *Model) resolveNames() {
+	if m.Resolver == nil {
+		return
+	}
+	for _, conn := range m.AllConnections {
+		switch connections.AddressScope(conn.RemoteIP) {
+		case "any", "loopback", "unknown":
+			continue
+		}
+		if name, ok := m.Resolver.Lookup(conn.RemoteIP); ok {
+			m.Names[conn.RemoteIP] = name
+		}
+	}
+}
@@ -12,28 +12,24 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mizerael/infsec_ssu/task_5/connections"
+	"github.com/mizerael/infsec_ssu/task_5/detect"
 	"github.com/mizerael/infsec_ssu/task_5/models"
+	"github.com/mizerael/infsec_ssu/task_5/resolve"
+	"github.com/mizerael/infsec_ssu/task_5/theme"
 )
 
 type Model models.AppModel
 
-func InitialModel() Model {
-	delegate := list.NewDefaultDelegate()
-	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.
-		Foreground(lipgloss.Color("229")).
-		BorderForeground(lipgloss.Color("229"))
-	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.
-		Foreground(lipgloss.Color("201"))
+func InitialModel(styles theme.Styles, collector models.Collector) Model {
+	delegate := newConnectionDelegate(styles)
 
 	l := list.New([]list.Item{}, delegate, 80, 20)
 	l.Title = "StatTUI (glamourous netstat)"
-	l.Styles.Title = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("62")).
-		Padding(0, 1)
+	l.Styles = styles.ListStyles
 
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
+	l.Filter = filterItems
 	l.SetShowHelp(false)
 	l.SetShowTitle(true)
 
@@ -55,26 +51,59 @@ func InitialModel() Model {
 		Width:           80,
 		Height:          24,
 		StatusMsg:       "Ready",
+		ShowServices:    true,
+		Styles:          styles,
+		Collector:       collector,
+		Host:            collector.Hostname(),
 	}
 }
 
+// WithResolver turns on reverse DNS: remote addresses are looked up in the
+// background and shown by name once known.
+func (m Model) WithResolver(r *resolve.Resolver) Model {
+	m.Resolver = r
+	m.Names = make(map[string]string)
+	m.ShowNames = true
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		m.getConnectionsCmd(),
 		m.tickCmd(),
-	)
+	}
+	if m.Resolver != nil {
+		cmds = append(cmds, m.waitForNameCmd())
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m Model) waitForNameCmd() tea.Cmd {
+	results := m.Resolver.Results()
+	return func() tea.Msg {
+		result := <-results
+		return models.NameResolvedMsg{IP: result.IP, Name: result.Name}
+	}
 }
 
 func (m Model) getConnectionsCmd() tea.Cmd {
 	return func() tea.Msg {
-		connections, err := connections.GetConnections(m.FilterState)
+		snapshot, err := m.Collector.Collect()
 		if err != nil {
 			return models.ConnectionErrorMsg(err.Error())
 		}
-		return models.ConnectionsLoadedMsg{
-			Connections: connections,
+		loaded := models.ConnectionsLoadedMsg{
+			Connections: connections.FilterConnections(snapshot.Connections, m.FilterState),
 			FilterState: m.FilterState,
+			Host:        snapshot.Host,
+			Processes:   snapshot.Processes,
+			Snapshot:    snapshot,
+			Alerts:      snapshot.Alerts,
+		}
+		if reporter, ok := m.Collector.(models.HealthReporter); ok {
+			loaded.Health = reporter.Health()
 		}
+		return loaded
 	}
 }
 
@@ -91,9 +120,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
 		m.Height = msg.Height
-		m.ConnectionsList.SetSize(msg.Width-4, max(10, msg.Height-12))
+		m.resizeList()
 
 	case tea.KeyMsg:
+		if m.Confirm != nil {
+			return m.handleConfirmMode(msg)
+		}
+		if m.Firewall != nil {
+			return m.handleFirewallMode(msg)
+		}
 		if m.InputMode {
 			return m.handleInputMode(msg)
 		}
@@ -109,18 +144,50 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case models.ConnectionsLoadedMsg:
+		if msg.Host != "" {
+			m.Host = msg.Host
+		}
 		m.Loading = false
 		m.ErrorMsg = ""
 		m.LastUpdate = time.Now()
 		m.StatusMsg = fmt.Sprintf("Loaded %d connections", len(msg.Connections))
-
-		items := make([]list.Item, len(msg.Connections))
-		for i, conn := range msg.Connections {
-			items[i] = list.Item(conn)
+		m.AllConnections = msg.Connections
+		m.Health = msg.Health
+		m.Events = appendEvents(m.Events, detect.AlertEvents(m.Alerts, msg.Alerts))
+		m.Alerts = msg.Alerts
+		m.resizeList()
+		m.ProcessRates = msg.Processes
+		m.Snapshot = msg.Snapshot
+		m.updateCounters(msg.Snapshot)
+		m.resolveNames()
+		m.setHosts(hostsOf(msg.Snapshot, msg.Health))
+
+		cmds = append(cmds, m.applyItems())
+
+	case models.NameResolvedMsg:
+		m.Names[msg.IP] = msg.Name
+		if m.ShowNames {
+			m.ConnectionsList.SetDelegate(m.delegate())
 		}
+		cmds = append(cmds, m.waitForNameCmd())
 
-		cmd := m.ConnectionsList.SetItems(items)
-		cmds = append(cmds, cmd)
+	case models.ActionDoneMsg:
+		if m.Firewall != nil {
+			m.Firewall.Status = msg.Status
+			if msg.Err != "" {
+				m.Firewall.Status = "Error: " + msg.Err
+			}
+		}
+		m.StatusMsg = msg.Status
+		if msg.Err != "" {
+			m.ErrorMsg = msg.Err
+			if msg.Status == "" {
+				m.StatusMsg = "Action failed"
+			}
+		} else {
+			m.Loading = true
+			cmds = append(cmds, m.getConnectionsCmd())
+		}
 
 	case models.ConnectionErrorMsg:
 		m.Loading = false
@@ -162,6 +229,13 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (Model, tea.Cmd) {
 }
 
 func (m Model) handleNormalMode(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// While the filter prompt is open every key is part of the query.
+	if m.ConnectionsList.FilterState() == list.Filtering {
+		var cmd tea.Cmd
+		m.ConnectionsList, cmd = m.ConnectionsList.Update(msg)
+		return m, cmd
+	}
+
 	keys := models.DefaultKeys()
 	var cmds []tea.Cmd
 
@@ -207,38 +281,114 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (Model, tea.Cmd) {
 		m.StatusMsg = "Already using netstat"
 		return m, nil
 
+	case key.Matches(msg, keys.NextHost), key.Matches(msg, keys.PrevHost):
+		if len(m.Hosts) < 2 {
+			return m, nil
+		}
+		tabs := len(m.Hosts) + 1
+		if key.Matches(msg, keys.NextHost) {
+			m.HostTab = (m.HostTab + 1) % tabs
+		} else {
+			m.HostTab = (m.HostTab + tabs - 1) % tabs
+		}
+		m.StatusMsg = "Viewing " + m.hostTabName(m.HostTab)
+		return m, m.applyItems()
+
+	case key.Matches(msg, keys.ToggleDetails):
+		m.ShowDetails = !m.ShowDetails
+		m.resizeList()
+		return m, nil
+
+	case key.Matches(msg, keys.ToggleMetrics):
+		m.ShowMetrics = !m.ShowMetrics
+		return m, m.applyItems()
+
+	case key.Matches(msg, keys.SortByRate):
+		m.SortByRate = !m.SortByRate
+		if m.SortByRate {
+			m.StatusMsg = "Sorted by bandwidth"
+		} else {
+			m.StatusMsg = "Default order"
+		}
+		return m, m.applyItems()
+
+	case key.Matches(msg, keys.ToggleNames):
+		if m.Resolver == nil {
+			m.StatusMsg = "Reverse DNS is off (start with -resolve)"
+			return m, nil
+		}
+		m.ShowNames = !m.ShowNames
+		if m.ShowNames {
+			m.StatusMsg = "Showing host names"
+		} else {
+			m.StatusMsg = "Showing addresses"
+		}
+		m.ConnectionsList.SetDelegate(m.delegate())
+		return m, nil
+
+	case key.Matches(msg, keys.ToggleServices):
+		m.ShowServices = !m.ShowServices
+		if m.ShowServices {
+			m.StatusMsg = "Showing service names"
+		} else {
+			m.StatusMsg = "Showing numeric ports"
+		}
+		m.ConnectionsList.SetDelegate(m.delegate())
+		return m, nil
+
+	case key.Matches(msg, keys.Kill):
+		return m.startAction(models.ActionSignal)
+
+	case key.Matches(msg, keys.Destroy):
+		return m.startAction(models.ActionDestroy)
+
+	case key.Matches(msg, keys.Firewall):
+		return m.openFirewall()
+
+	case key.Matches(msg, keys.NextView):
+		m.ActiveView = m.ActiveView.Next()
+		m.StatusMsg = "View: " + m.ActiveView.String()
+		return m, nil
+
 	case key.Matches(msg, keys.ToggleHelp):
 		m.ShowHelp = !m.ShowHelp
 		return m, nil
 
 	default:
-		m.ConnectionsList, _ = m.ConnectionsList.Update(msg)
-		return m, nil
+		var cmd tea.Cmd
+		m.ConnectionsList, cmd = m.ConnectionsList.Update(msg)
+		return m, cmd
 	}
 }
 
 func (m Model) View() string {
+	if m.Confirm != nil {
+		return m.renderConfirm()
+	}
+	if m.Firewall != nil {
+		return m.renderFirewall()
+	}
 	if m.InputMode {
 		return m.renderInputMode()
 	}
 
 	var s strings.Builder
 
-	titleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("62")).
-		Padding(0, 1).
-		Bold(true).
+	titleStyle := m.Styles.Title.
 		Width(m.Width).
 		Align(lipgloss.Center)
 
-	s.WriteString(titleStyle.Render("StatTUI (netstat)"))
+	title := "StatTUI (netstat)"
+	if m.ActiveView != models.ViewConnections {
+		title += " · " + m.ActiveView.String()
+	}
+	if m.Host != "" {
+		title += " @ " + m.Host
+	}
+	s.WriteString(titleStyle.Render(title))
 	s.WriteString("\n")
 
-	statusStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("243")).
-		Italic(true).
-		Padding(0, 1)
+	statusStyle := m.Styles.Status
 
 	autoRefreshStatus := "ON"
 	if !m.AutoRefresh {
@@ -253,38 +403,63 @@ func (m Model) View() string {
 		m.StatusMsg,
 	)
 
-	if m.ErrorMsg != "" {
-		status += fmt.Sprintf(" | Error: %s", m.ErrorMsg)
-	}
-
 	if m.Loading {
 		status += " | Loading..."
 	}
 
+	if m.SortByRate {
+		status += " | Top: " + m.topTalkers(3)
+	}
+
+	if m.ErrorMsg != "" {
+		status += " |"
+	}
+
 	s.WriteString(statusStyle.Render(status))
+	if m.ErrorMsg != "" {
+		s.WriteString(m.Styles.Error.Render(fmt.Sprintf("Error: %s", m.ErrorMsg)))
+	}
 	s.WriteString("\n")
 
-	listView := m.ConnectionsList.View()
-	s.WriteString(listView)
+	if len(m.Hosts) > 1 {
+		s.WriteString(m.renderHostTabs())
+		s.WriteString("\n")
+	}
+	if len(m.Health) > 0 {
+		s.WriteString(m.renderHealth())
+		s.WriteString("\n")
+	}
+	if len(m.Alerts) > 0 {
+		s.WriteString(m.renderAlerts(maxAlertLines))
+		s.WriteString("\n")
+	}
+
+	switch m.ActiveView {
+	case models.ViewDashboard:
+		s.WriteString(m.renderDashboard())
+	case models.ViewCounters:
+		s.WriteString(m.renderCounters())
+	case models.ViewEvents:
+		s.WriteString(m.renderEvents())
+	case models.ViewConntrack:
+		s.WriteString(m.renderConntrack())
+	case models.ViewInterfaces:
+		s.WriteString(m.renderInterfaces())
+	default:
+		s.WriteString(m.ConnectionsList.View())
+	}
+	if m.ShowDetails && m.ActiveView == models.ViewConnections {
+		s.WriteString("\n")
+		s.WriteString(m.renderDetails())
+	}
 	if m.ShowHelp {
-		helpStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("241")).
-			Padding(0, 1).
-			Width(80)
-
-		navLine := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("255")).
-			Bold(true).
-			Render("Navigation: ") +
-			"↑/k ↓/j • PgUp/PgDn • Home/End • / search • Esc cancel"
-
-		cmdLine := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("255")).
-			Bold(true).
-			Render("Commands: ") +
-			"f filter • r refresh • a auto-refresh • i interval • ? help • q quit"
+		helpStyle := m.Styles.Help.Width(80)
+
+		navLine := m.Styles.HelpKey.Render("Navigation: ") +
+			"↑/k ↓/j • PgUp/PgDn • Home/End • / search (port: service: exe: sha256: country:!RU asn: org: city: iface:) • Esc cancel"
+
+		cmdLine := m.Styles.HelpKey.Render("Commands: ") +
+			"tab view • f filter • r refresh • a auto-refresh • i interval • [/] host • d details • m metrics • s sort by rate • n names • p services • K signal • X destroy • F firewall • ? help • q quit"
 
 		helpContent := navLine + "\n" + cmdLine
 
@@ -295,14 +470,18 @@ func (m Model) View() string {
 	return s.String()
 }
 
+func (m *Model) resizeList() {
+	height := m.Height - 12 - alertLines(len(m.Alerts))
+	if m.ShowDetails {
+		height -= detailsHeight
+	}
+	m.ConnectionsList.SetSize(m.Width-4, max(10, height))
+}
+
 func (m Model) renderInputMode() string {
 	var s strings.Builder
 
-	titleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("62")).
-		Padding(0, 1).
-		Bold(true).
+	titleStyle := m.Styles.Title.
 		Width(m.Width - 1).
 		Align(lipgloss.Center)
 